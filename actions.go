@@ -0,0 +1,62 @@
+package baccess
+
+import (
+	"slices"
+	"strings"
+)
+
+// AllowedActions returns the actions subject may perform on resource, for
+// UIs that need to decide which controls to render. Every base action named
+// by a policy key ("read", "delete" for "delete:isOwner", "update" for
+// "update:*") is evaluated as a request without a condition, exactly as
+// Evaluate would evaluate it, and the granted ones are returned in name
+// order.
+//
+// Under HierarchyMatcher, patterns with "*" segments are evaluated too, so a
+// granted "billing.*" is listed as a subtree; actions below it that are
+// denied are left out.
+//
+// everything reports whether the global "*" policies grant any action,
+// including ones that no policy key names; the returned list still only
+// contains the named actions, minus any that are denied.
+func (e *Evaluator[S, R]) AllowedActions(subject S, resource R) (actions []string, everything bool) {
+	set := e.load()
+	req := AccessRequest[S, R]{Subject: subject, Resource: resource, Action: "*"}
+
+	if global := set.index.wildcards(); len(global) > 0 {
+		everything, _ = e.evaluate(set, global, req)
+	}
+
+	for _, base := range set.index.actionNames() {
+		req.Action = base
+		if allowed, _ := e.evaluate(set, set.index.candidates(base), req); allowed {
+			actions = append(actions, base)
+		}
+	}
+
+	return actions, everything
+}
+
+// AllowedActions lists the actions granted by the currently published
+// evaluator. It grants nothing if no evaluator has been published.
+func (h *EvaluatorHolder[S, R]) AllowedActions(subject S, resource R) ([]string, bool) {
+	e := h.current.Load()
+	if e == nil {
+		return nil, false
+	}
+
+	return e.AllowedActions(subject, resource)
+}
+
+// namedActions returns the sorted base actions of the indexed policy keys.
+func namedActions(bases map[string]*baseIndex) []string {
+	actions := make([]string, 0, len(bases))
+	for base := range bases {
+		if base != "" && !strings.Contains(base, "*") {
+			actions = append(actions, base)
+		}
+	}
+	slices.Sort(actions)
+
+	return actions
+}
@@ -0,0 +1,79 @@
+package baccess_test
+
+import (
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluator_AllowedActions(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+			"isDraft": isDraft(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"admin":      {Allow: []string{"*"}, Deny: []string{"purge"}},
+			"editor":     {Allow: []string{"read", "update:*", "delete:isOwner", "publish:isOwner&isDraft"}},
+			"viewer":     {Allow: []string{"read"}},
+			"contractor": {Deny: []string{"delete"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	require.NoError(t, err)
+
+	owned := auth_test_utils.MockResource{OwnerID: "u1", Status: "draft"}
+	foreign := auth_test_utils.MockResource{OwnerID: "u2", Status: "published"}
+	subject := func(roles ...string) auth_test_utils.MockSubject {
+		return auth_test_utils.MockSubject{ID: "u1", Roles: roles}
+	}
+
+	tests := []struct {
+		name       string
+		subject    auth_test_utils.MockSubject
+		resource   auth_test_utils.MockResource
+		actions    []string
+		everything bool
+	}{
+		{"viewer", subject("viewer"), owned, []string{"read"}, false},
+		{"editor owning a draft", subject("editor"), owned, []string{"delete", "publish", "read", "update"}, false},
+		{"editor on a foreign document", subject("editor"), foreign, []string{"read", "update"}, false},
+		{"contractor editor", subject("editor", "contractor"), owned, []string{"publish", "read", "update"}, false},
+		{"admin", subject("admin"), foreign, []string{"delete", "publish", "read", "update"}, true},
+		{"no roles", subject(), owned, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, everything := evaluator.AllowedActions(tt.subject, tt.resource)
+			assert.Equal(t, tt.actions, actions)
+			assert.Equal(t, tt.everything, everything)
+
+			// Every returned action is allowed by Evaluate.
+			for _, action := range actions {
+				assert.True(t, evaluator.Evaluate(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+					Subject: tt.subject, Resource: tt.resource, Action: action,
+				}), action)
+			}
+		})
+	}
+
+	// A global deny revokes the "everything" grant.
+	evaluator.AddDenyPolicy("*", func(req baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]) bool {
+		return req.Resource.Status == "locked"
+	})
+	actions, everything := evaluator.AllowedActions(subject("admin"), auth_test_utils.MockResource{Status: "locked"})
+	assert.Empty(t, actions)
+	assert.False(t, everything)
+
+	holder := baccess.NewEvaluatorHolder[auth_test_utils.MockSubject, auth_test_utils.MockResource](nil)
+	actions, everything = holder.AllowedActions(subject("admin"), owned)
+	assert.Nil(t, actions)
+	assert.False(t, everything)
+}
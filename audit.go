@@ -0,0 +1,224 @@
+package baccess
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditEvent records a single authorization decision.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// SubjectID and ResourceID are read through Identifiable.GetID, and are
+	// nil for subjects and resources that do not implement it.
+	SubjectID  any    `json:"subject_id"`
+	ResourceID any    `json:"resource_id"`
+	Action     string `json:"action"`
+	Allowed    bool   `json:"allowed"`
+	// Effect is the effect of the deciding policy, or EffectDeny when no
+	// policy applied.
+	Effect Effect `json:"effect"`
+	// Policy is the key of the deciding policy; it is empty when access fell
+	// through to the implicit deny.
+	Policy string `json:"policy,omitempty"`
+	// Matched lists the keys of every policy matching the action, in
+	// evaluation order.
+	Matched []string      `json:"matched"`
+	Latency time.Duration `json:"latency_ns"`
+	// Error is set when EvaluateContext failed.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditEvent after every decision made by Evaluate,
+// EvaluateContext and DecideContext, and for every resource Filter and
+// FilterSeq evaluate. Record is called on the request path, possibly
+// concurrently, so sinks that do I/O should be wrapped with NewAsyncSink.
+// Explain, AllowedActions and PartialEvaluate are queries about decisions and
+// are not recorded.
+type AuditSink interface {
+	Record(event AuditEvent)
+}
+
+// WithAuditSink sends every decision to sink.
+func WithAuditSink(sink AuditSink) EvaluatorOption {
+	return func(o *evaluatorOptions) {
+		o.auditSink = sink
+	}
+}
+
+// audit builds the AuditEvent for a decision and passes it to the sink.
+func (e *Evaluator[S, R]) audit(
+	set *policySet[S, R],
+	candidates []int32,
+	req AccessRequest[S, R],
+	allowed bool,
+	decisive int32,
+	start time.Time,
+	err error,
+) {
+	event := newAuditEvent(req, allowed, start)
+	event.Matched = make([]string, len(candidates))
+	for i, c := range candidates {
+		event.Matched[i] = set.policies[c].key
+	}
+	if decisive != -1 {
+		event.Effect = set.policies[decisive].effect
+		event.Policy = set.policies[decisive].key
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	e.auditSink.Record(event)
+}
+
+// newAuditEvent describes the decision on req made since start, as made by
+// the implicit deny.
+func newAuditEvent[S any, R any](req AccessRequest[S, R], allowed bool, start time.Time) AuditEvent {
+	event := AuditEvent{
+		Time:    start,
+		Action:  req.Action,
+		Allowed: allowed,
+		Effect:  EffectDeny,
+		Latency: time.Since(start),
+	}
+	if id, ok := any(req.Subject).(Identifiable); ok {
+		event.SubjectID = id.GetID()
+	}
+	if id, ok := any(req.Resource).(Identifiable); ok {
+		event.ResourceID = id.GetID()
+	}
+
+	return event
+}
+
+// SlogSink logs every decision as an "authorization decision" record.
+type SlogSink struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogSink logs decisions to logger at level.
+func NewSlogSink(logger *slog.Logger, level slog.Level) *SlogSink {
+	return &SlogSink{logger: logger, level: level}
+}
+
+func (s *SlogSink) Record(event AuditEvent) {
+	attrs := []slog.Attr{
+		slog.Any("subject_id", event.SubjectID),
+		slog.Any("resource_id", event.ResourceID),
+		slog.String("action", event.Action),
+		slog.Bool("allowed", event.Allowed),
+		slog.String("effect", string(event.Effect)),
+		slog.String("policy", event.Policy),
+		slog.Any("matched", event.Matched),
+		slog.Duration("latency", event.Latency),
+	}
+	if event.Error != "" {
+		attrs = append(attrs, slog.String("error", event.Error))
+	}
+
+	s.logger.LogAttrs(context.Background(), s.level, "authorization decision", attrs...)
+}
+
+// AsyncSink hands events to another sink on a background goroutine, so that
+// a slow sink never delays evaluation. When its buffer is full, events are
+// dropped and counted instead.
+type AsyncSink struct {
+	sink    AuditSink
+	events  chan AuditEvent
+	dropped atomic.Uint64
+	onDrop  func(dropped uint64)
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+type AsyncSinkOption func(*AsyncSink)
+
+// WithDropHook calls hook, on the background goroutine, with the number of
+// events dropped since the previous call, once the buffer has room again.
+func WithDropHook(hook func(dropped uint64)) AsyncSinkOption {
+	return func(s *AsyncSink) {
+		s.onDrop = hook
+	}
+}
+
+// NewAsyncSink starts forwarding events to sink through a buffer of the
+// given size; a negative size is treated as zero. Call Close to flush the
+// buffer and stop the goroutine.
+func NewAsyncSink(sink AuditSink, buffer int, opts ...AsyncSinkOption) *AsyncSink {
+	s := &AsyncSink{
+		sink:   sink,
+		events: make(chan AuditEvent, max(buffer, 0)),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	var reported uint64
+	for event := range s.events {
+		if s.onDrop != nil {
+			if dropped := s.dropped.Load(); dropped != reported {
+				s.onDrop(dropped - reported)
+				reported = dropped
+			}
+		}
+		s.sink.Record(event)
+	}
+	if s.onDrop != nil {
+		if dropped := s.dropped.Load(); dropped != reported {
+			s.onDrop(dropped - reported)
+		}
+	}
+}
+
+// Record queues event without blocking. Events recorded after Close are
+// dropped.
+func (s *AsyncSink) Record(event AuditEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns the total number of events dropped so far.
+func (s *AsyncSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops accepting events and waits until the queued ones have been
+// passed to the wrapped sink.
+func (s *AsyncSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+
+	return nil
+}
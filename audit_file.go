@@ -0,0 +1,170 @@
+package baccess
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FileSink appends every decision to a file as one JSON object per line,
+// rotating the file when it grows past a size limit: path is renamed to
+// path.1, path.1 to path.2 and so on, keeping a fixed number of backups.
+// If rotating or reopening the file fails, later events are appended to path
+// as soon as it can be opened again.
+type FileSink struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	closed     bool
+	err        error
+}
+
+type FileSinkOption func(*FileSink)
+
+// WithMaxFileSize rotates the file before a write would take it past size
+// bytes (default 100 MiB). Zero disables rotation.
+func WithMaxFileSize(size int64) FileSinkOption {
+	return func(s *FileSink) {
+		s.maxSize = size
+	}
+}
+
+// WithMaxBackups sets how many rotated files are kept (default 5).
+func WithMaxBackups(n int) FileSinkOption {
+	return func(s *FileSink) {
+		s.maxBackups = n
+	}
+}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string, opts ...FileSinkOption) (*FileSink, error) {
+	s := &FileSink{path: path, maxSize: 100 << 20, maxBackups: 5}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	s.file, s.size = file, info.Size()
+
+	return nil
+}
+
+// rotate closes the current file, shifts the backups and opens a new file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	s.file = nil
+
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+		return s.open()
+	}
+
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return s.open()
+}
+
+// Record writes event as a line of JSON. Write errors are kept for Err.
+func (s *FileSink) Record(event AuditEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.fail(fmt.Errorf("failed to encode audit event: %w", err))
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		if s.err == nil {
+			s.err = errors.New("audit log is closed")
+		}
+		return
+	}
+	if s.file != nil && s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			s.err = err
+		}
+	}
+	// A failed rotation leaves no file open. Appending to path keeps the
+	// event, at the cost of exceeding the size limit until a rotation succeeds.
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			s.err = err
+			return
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		s.err = fmt.Errorf("failed to write audit log: %w", err)
+	}
+}
+
+func (s *FileSink) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// Err returns the most recent error encountered while writing, if any.
+func (s *FileSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close closes the file. Events recorded afterwards are lost and reported
+// by Err.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
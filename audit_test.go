@@ -0,0 +1,340 @@
+package baccess_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []baccess.AuditEvent
+}
+
+func (s *recordingSink) Record(event baccess.AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+}
+
+func (s *recordingSink) last() baccess.AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.events[len(s.events)-1]
+}
+
+func TestEvaluator_AuditSink(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	sink := &recordingSink{}
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{
+			"isOwner": isOwner(),
+			"isDraft": isDraft(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor": {Allow: []string{"edit:isOwner"}, Deny: []string{"edit:isDraft"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider, baccess.WithAuditSink(sink))
+	require.NoError(t, err)
+
+	editor := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	owned := auth_test_utils.MockResource{ID: "doc1", OwnerID: "u1", Status: "published"}
+
+	assert.True(t, evaluator.Evaluate(request{Subject: editor, Resource: owned, Action: "edit"}))
+	event := sink.last()
+	assert.Equal(t, "u1", event.SubjectID)
+	assert.Equal(t, "doc1", event.ResourceID)
+	assert.Equal(t, "edit", event.Action)
+	assert.True(t, event.Allowed)
+	assert.Equal(t, baccess.EffectAllow, event.Effect)
+	assert.Equal(t, "edit:isOwner", event.Policy)
+	assert.Equal(t, []string{"edit:isDraft", "edit:isOwner"}, event.Matched)
+	assert.False(t, event.Time.IsZero())
+	assert.GreaterOrEqual(t, event.Latency, time.Duration(0))
+
+	draft := auth_test_utils.MockResource{ID: "doc2", OwnerID: "u1", Status: "draft"}
+	assert.False(t, evaluator.Evaluate(request{Subject: editor, Resource: draft, Action: "edit"}))
+	event = sink.last()
+	assert.False(t, event.Allowed)
+	assert.Equal(t, baccess.EffectDeny, event.Effect)
+	assert.Equal(t, "edit:isDraft", event.Policy)
+
+	assert.False(t, evaluator.Evaluate(request{Subject: editor, Resource: owned, Action: "delete"}))
+	event = sink.last()
+	assert.Equal(t, baccess.EffectDeny, event.Effect)
+	assert.Empty(t, event.Policy)
+	assert.Empty(t, event.Matched)
+
+	// EvaluateContext records failures.
+	evaluator.AddContextPolicy("share", func(ctx context.Context, req request) (bool, error) {
+		return false, errBackend
+	})
+	_, err = evaluator.EvaluateContext(context.Background(), request{Subject: editor, Resource: owned, Action: "share"})
+	require.Error(t, err)
+	event = sink.last()
+	assert.False(t, event.Allowed)
+	assert.Equal(t, `policy "share": group service unavailable`, event.Error)
+
+	allowed, err := evaluator.EvaluateContext(context.Background(), request{Subject: editor, Resource: owned, Action: "edit"})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "edit:isOwner", sink.last().Policy)
+
+	// Queries about decisions are not recorded.
+	recorded := len(sink.events)
+	evaluator.Explain(request{Subject: editor, Resource: owned, Action: "edit"})
+	evaluator.AllowedActions(editor, owned)
+	assert.Len(t, sink.events, recorded)
+
+	// Subjects and resources that are not Identifiable have no IDs.
+	anonymous := baccess.NewEvaluator[string, int](
+		baccess.WithCombiningAlgorithm(baccess.FirstApplicable),
+		baccess.WithAuditSink(sink),
+	)
+	anonymous.AddDenyPolicy("read", func(req baccess.AccessRequest[string, int]) bool { return req.Resource < 0 })
+	anonymous.AddPolicy("*", func(req baccess.AccessRequest[string, int]) bool { return true })
+	assert.True(t, anonymous.Evaluate(baccess.AccessRequest[string, int]{Subject: "alice", Resource: 1, Action: "read"}))
+	event = sink.last()
+	assert.Nil(t, event.SubjectID)
+	assert.Nil(t, event.ResourceID)
+	assert.Equal(t, "*", event.Policy)
+	assert.Equal(t, []string{"read", "*"}, event.Matched)
+}
+
+func TestEvaluator_AuditSink_Filter(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	sink := &recordingSink{}
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{
+			"isOwner": isOwner(),
+			"isDraft": isDraft(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor":  {Allow: []string{"edit:isOwner"}, Deny: []string{"edit:isDraft"}},
+			"auditor": {Allow: []string{"edit"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider, baccess.WithAuditSink(sink))
+	require.NoError(t, err)
+
+	editor := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	resources := []auth_test_utils.MockResource{
+		{ID: "doc1", OwnerID: "u1", Status: "published"},
+		{ID: "doc2", OwnerID: "u1", Status: "draft"},
+		{ID: "doc3", OwnerID: "u2", Status: "published"},
+	}
+
+	// Filter records the same events as Evaluate on every resource.
+	for _, resource := range resources {
+		evaluator.Evaluate(request{Subject: editor, Resource: resource, Action: "edit"})
+	}
+	want := sink.events
+	withoutTimes := func(events []baccess.AuditEvent) []baccess.AuditEvent {
+		events = slices.Clone(events)
+		for i := range events {
+			events[i].Time, events[i].Latency = time.Time{}, 0
+		}
+		slices.SortFunc(events, func(a, b baccess.AuditEvent) int {
+			return strings.Compare(a.ResourceID.(string), b.ResourceID.(string))
+		})
+		return events
+	}
+
+	for name, filter := range map[string]func(){
+		"filter":          func() { evaluator.Filter(editor, "edit", resources) },
+		"filter parallel": func() { evaluator.Filter(editor, "edit", resources, baccess.WithParallelism(4)) },
+		"seq":             func() { _ = slices.Collect(evaluator.FilterSeq(editor, "edit", slices.Values(resources))) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			sink.events = nil
+			filter()
+			assert.Equal(t, withoutTimes(want), withoutTimes(sink.events))
+		})
+	}
+	assert.Equal(t, "edit:isOwner", want[0].Policy)
+	assert.Equal(t, "edit:isDraft", want[1].Policy)
+	assert.Empty(t, want[2].Policy)
+	assert.Equal(t, []string{"edit", "edit:isDraft", "edit:isOwner"}, want[2].Matched)
+
+	// Subjects without a matching role are still recorded, as denied.
+	sink.events = nil
+	assert.Empty(t, evaluator.Filter(auth_test_utils.MockSubject{ID: "u2"}, "edit", resources))
+	require.Len(t, sink.events, 3)
+	assert.False(t, sink.events[0].Allowed)
+	assert.Empty(t, sink.events[0].Policy)
+}
+
+func TestSlogSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := baccess.NewSlogSink(slog.New(slog.NewJSONHandler(&buf, nil)), slog.LevelInfo)
+	sink.Record(baccess.AuditEvent{
+		SubjectID: "u1",
+		Action:    "edit",
+		Allowed:   true,
+		Effect:    baccess.EffectAllow,
+		Policy:    "edit:isOwner",
+		Matched:   []string{"edit:isOwner"},
+	})
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "authorization decision", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "u1", record["subject_id"])
+	assert.Nil(t, record["resource_id"])
+	assert.Equal(t, true, record["allowed"])
+	assert.Equal(t, "edit:isOwner", record["policy"])
+	assert.Equal(t, []any{"edit:isOwner"}, record["matched"])
+	assert.NotContains(t, record, "error")
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := baccess.NewFileSink(path, baccess.WithMaxFileSize(400), baccess.WithMaxBackups(2))
+	require.NoError(t, err)
+
+	for i := range 20 {
+		sink.Record(baccess.AuditEvent{SubjectID: i, Action: "read", Effect: baccess.EffectDeny})
+	}
+	require.NoError(t, sink.Err())
+	require.NoError(t, sink.Close())
+
+	// Lines are never split across files, the backups are limited and the
+	// newest events are in the current file.
+	var lines []map[string]any
+	for _, name := range []string{path + ".2", path + ".1", path} {
+		file, err := os.Open(name)
+		require.NoError(t, err)
+		info, err := file.Stat()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(400))
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		file.Close()
+	}
+	assert.NoFileExists(t, path+".3")
+	require.NotEmpty(t, lines)
+	assert.Less(t, len(lines), 20)
+	assert.Equal(t, float64(19), lines[len(lines)-1]["subject_id"])
+	assert.Equal(t, "read", lines[0]["action"])
+
+	sink.Record(baccess.AuditEvent{})
+	assert.EqualError(t, sink.Err(), "audit log is closed")
+
+	// Appending to an existing file continues where it left off.
+	sink, err = baccess.NewFileSink(path)
+	require.NoError(t, err)
+	sink.Record(baccess.AuditEvent{Action: "write"})
+	require.NoError(t, sink.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"subject_id":19`)
+	assert.Contains(t, string(data), `"action":"write"`)
+}
+
+func TestFileSink_RotateFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := baccess.NewFileSink(path, baccess.WithMaxFileSize(100), baccess.WithMaxBackups(1))
+	require.NoError(t, err)
+
+	// A directory in the way of the backup makes the rotation fail.
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocked"), 0o755))
+	for i := range 5 {
+		sink.Record(baccess.AuditEvent{SubjectID: i, Action: "read"})
+	}
+	assert.ErrorContains(t, sink.Err(), "failed to rotate audit log")
+
+	// Events are still appended to the current file.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, strings.Count(string(data), "\n"))
+
+	// Once the way is clear the file is rotated again.
+	require.NoError(t, os.RemoveAll(path+".1"))
+	sink.Record(baccess.AuditEvent{SubjectID: 5, Action: "read"})
+	require.NoError(t, sink.Close())
+	assert.FileExists(t, path+".1")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"subject_id":5`)
+	assert.NotContains(t, string(data), `"subject_id":4`)
+}
+
+// blockingSink blocks every Record until release is closed.
+type blockingSink struct {
+	recordingSink
+	release chan struct{}
+}
+
+func (s *blockingSink) Record(event baccess.AuditEvent) {
+	<-s.release
+	s.recordingSink.Record(event)
+}
+
+func TestAsyncSink(t *testing.T) {
+	inner := &blockingSink{release: make(chan struct{})}
+	var reported []uint64
+	sink := baccess.NewAsyncSink(inner, 4, baccess.WithDropHook(func(dropped uint64) {
+		reported = append(reported, dropped)
+	}))
+
+	// Recording never blocks, even though the wrapped sink is stuck.
+	for i := range 20 {
+		sink.Record(baccess.AuditEvent{SubjectID: i})
+	}
+	assert.GreaterOrEqual(t, sink.Dropped(), uint64(15))
+
+	close(inner.release)
+	require.NoError(t, sink.Close())
+	assert.Equal(t, 20, len(inner.events)+int(sink.Dropped()))
+	assert.Equal(t, 0, inner.events[0].SubjectID)
+
+	var total uint64
+	for _, n := range reported {
+		total += n
+	}
+	assert.Equal(t, sink.Dropped(), total)
+
+	sink.Record(baccess.AuditEvent{})
+	assert.Equal(t, total+1, sink.Dropped())
+	require.NoError(t, sink.Close())
+}
+
+func TestAsyncSink_NegativeBuffer(t *testing.T) {
+	inner := &recordingSink{}
+	sink := baccess.NewAsyncSink(inner, -1)
+	for i := range 10 {
+		sink.Record(baccess.AuditEvent{SubjectID: i})
+	}
+	require.NoError(t, sink.Close())
+	assert.Equal(t, 10, len(inner.events)+int(sink.Dropped()))
+}
@@ -0,0 +1,30 @@
+package main
+
+// Entity adapts an arbitrary JSON object to the baccess interfaces, so that
+// services in any language can send subjects and resources as plain
+// documents. "id" is the entity's ID, "roles" its roles, and every field is
+// an attribute that conditions can read, e.g. "subject.department" or
+// "resource.owner.id".
+type Entity map[string]any
+
+func (e Entity) GetID() any {
+	return e["id"]
+}
+
+// GetRoles returns the strings in the "roles" field, ignoring other values.
+func (e Entity) GetRoles() []string {
+	values, _ := e["roles"].([]any)
+
+	roles := make([]string, 0, len(values))
+	for _, v := range values {
+		if role, ok := v.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+func (e Entity) GetAttribute(key string) any {
+	return e[key]
+}
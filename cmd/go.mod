@@ -2,6 +2,15 @@ module github.com/brian-nunez/baccess/cmd
 
 go 1.25.0
 
-require github.com/brian-nunez/baccess v1.0.1
+require (
+	github.com/brian-nunez/baccess v1.0.1
+	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
 
 replace github.com/brian-nunez/baccess => ../
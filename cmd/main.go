@@ -2,141 +2,42 @@ package main
 
 import (
 	"fmt"
-	"log"
-
-	"github.com/brian-nunez/baccess"
+	"os"
 )
 
-type User struct {
-	ID    string
-	Roles []string
-	Attrs map[string]any
-}
-
-func (u User) GetID() any {
-	return u.ID
-}
-
-func (u User) GetRoles() []string {
-	return u.Roles
-}
+const usage = `Usage: baccess <command> [flags]
 
-func (u User) GetAttribute(key string) any {
-	return u.Attrs[key]
-}
+Commands:
+  serve   run a policy decision point over HTTP/JSON
+  test    check a policy file against test cases
+  demo    evaluate example requests against config.json (the default)
 
-type Document struct {
-	ID            string
-	OwnerID       string
-	Public        bool
-	Collaborators []string
-}
-
-func loadConfigFromFile() *baccess.Config {
-	cfg, _ := baccess.LoadConfigFromFile("config.json")
-
-	return cfg
-}
-
-func loadConfig() *baccess.Config {
-	cfgData := map[string]any{
-		"policies": map[string]any{
-			"admin": map[string]any{
-				"allow": []string{"*"},
-			},
-			"editor": map[string]any{
-				"allow": []string{
-					"read:*",
-					"write:*",
-					"delete:isOwner",
-					"edit",
-					// "edit:isOwner",
-					// "edit:isCollaborator",
-				},
-			},
-			"viewer": map[string]any{
-				"allow": []string{"read:*"},
-			},
-		},
-	}
-
-	cfg, _ := baccess.LoadConfigFromMap(cfgData)
-
-	return cfg
-}
+Run "baccess <command> -h" for the flags of a command.
+`
 
 func main() {
-	rbac := baccess.NewRBAC[User, Document]()
-
-	registry := baccess.NewRegistry[User, Document]()
-
-	registry.Register("isOwner", baccess.FieldEquals(
-		func(u User) string { return u.ID },
-		func(d Document) string { return d.OwnerID },
-	))
-
-	registry.Register("isCollaborator", baccess.SubjectInResourceList(
-		func(u User) string { return u.ID },
-		func(d Document) []string { return d.Collaborators },
-	))
-
-	registry.Register("isPublic", baccess.ResourceMatches[User, Document, bool](
-		func(d Document) bool { return d.Public },
-		true,
-	))
-
-	cfg := loadConfigFromFile() // or loadConfig()
-
-	evaluator, err := baccess.BuildEvaluator(cfg, rbac, registry)
-	if err != nil {
-		log.Fatalf("Error building evaluator: %v", err)
+	if len(os.Args) < 2 {
+		runDemo()
+		return
 	}
 
-	admin := User{ID: "admin1", Roles: []string{"admin"}}
-	editor := User{ID: "editor1", Roles: []string{"editor"}}
-	editor2 := User{ID: "editor2", Roles: []string{"editor"}}
-	viewer := User{ID: "viewer1", Roles: []string{"viewer"}}
-	other := User{ID: "other1", Roles: []string{"viewer"}}
-
-	doc1 := Document{
-		ID:            "doc1",
-		OwnerID:       "editor1",
-		Public:        false,
-		Collaborators: []string{"editor2"},
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "serve":
+		err = runServe(args)
+	case "test":
+		err = runTest(args, os.Stdout)
+	case "demo":
+		runDemo()
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
 
-	fmt.Println("--- Testing Policies from Config ---")
-
-	req1 := baccess.AccessRequest[User, Document]{Subject: admin, Resource: doc1, Action: "read"}
-	fmt.Printf("Admin read doc1: %v (Expected: true)\n", evaluator.Evaluate(req1))
-
-	req2 := baccess.AccessRequest[User, Document]{Subject: editor, Resource: doc1, Action: "read"}
-	fmt.Printf("Editor read doc1: %v (Expected: true)\n", evaluator.Evaluate(req2))
-
-	req3 := baccess.AccessRequest[User, Document]{Subject: viewer, Resource: doc1, Action: "read"}
-	fmt.Printf("Viewer read doc1: %v (Expected: true)\n", evaluator.Evaluate(req3))
-
-	req4 := baccess.AccessRequest[User, Document]{Subject: editor, Resource: doc1, Action: "delete"}
-	fmt.Printf("Editor delete own doc1: %v (Expected: true)\n", evaluator.Evaluate(req4))
-
-	req5 := baccess.AccessRequest[User, Document]{Subject: other, Resource: doc1, Action: "delete"}
-	fmt.Printf("Viewer delete doc1: %v (Expected: false)\n", evaluator.Evaluate(req5))
-
-	req6 := baccess.AccessRequest[User, Document]{Subject: admin, Resource: doc1, Action: "delete"}
-	fmt.Printf("Admin delete doc1: %v (Expected: true)\n", evaluator.Evaluate(req6))
-
-	req7 := baccess.AccessRequest[User, Document]{Subject: admin, Resource: doc1, Action: "nuke"}
-	fmt.Printf("Admin nuke doc1: %v (Expected: true, wildcards apply)\n", evaluator.Evaluate(req7))
-
-	req8 := baccess.AccessRequest[User, Document]{Subject: editor, Resource: doc1, Action: "nuke"}
-	fmt.Printf("Editor nuke doc1: %v (Expected: false)\n", evaluator.Evaluate(req8))
-
-	req9 := baccess.AccessRequest[User, Document]{Subject: editor, Resource: doc1, Action: "edit:isOwner"}
-	fmt.Printf("Editor1 edit own doc1: %v (Expected: true)\n", evaluator.Evaluate(req9))
-
-	req10 := baccess.AccessRequest[User, Document]{Subject: editor2, Resource: doc1, Action: "edit"}
-	fmt.Printf("Editor2 edit shared doc1: %v (Expected: true)\n", evaluator.Evaluate(req10))
-
-	req11 := baccess.AccessRequest[User, Document]{Subject: other, Resource: doc1, Action: "edit"}
-	fmt.Printf("Other edit doc1: %v (Expected: false)\n", evaluator.Evaluate(req11))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/brian-nunez/baccess"
+	"gopkg.in/yaml.v3"
+)
+
+// testSuite is a policy test file. Subjects and resources are declared once
+// by name and referenced from the cases.
+type testSuite struct {
+	Subjects  map[string]Entity `json:"subjects"`
+	Resources map[string]Entity `json:"resources,omitempty"`
+	Tests     []testCase        `json:"tests"`
+}
+
+type testCase struct {
+	Name        string              `json:"name,omitempty"`
+	Subject     string              `json:"subject"`
+	Resource    string              `json:"resource,omitempty"`
+	Action      string              `json:"action"`
+	Environment baccess.Environment `json:"environment,omitempty"`
+	// Expect is "allow" or "deny".
+	Expect string `json:"expect"`
+}
+
+// testFailure is a case whose decision differs from the expected one.
+type testFailure struct {
+	name     string
+	test     testCase
+	decision baccess.Decision
+}
+
+// runTest evaluates the cases in each test file against a policy file and
+// reports the failing ones, so that policy changes can be checked in CI.
+func runTest(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: baccess test -config <policy file> <test file>...")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "policy file (JSON or YAML), required")
+	verbose := flags.Bool("v", false, "list every case, not only the failures")
+	cover := flags.Bool("cover", false, "print which policy rules the cases exercised")
+	coverHTML := flags.String("coverhtml", "", "write the coverage report as HTML to `file`")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *configPath == "" {
+		return errors.New("test: -config is required")
+	}
+	if flags.NArg() == 0 {
+		return errors.New("test: at least one test file is required")
+	}
+
+	cfg, err := baccess.LoadConfigFromFile(*configPath)
+	if err != nil {
+		return fmt.Errorf("test: %w", err)
+	}
+	var opts []baccess.EvaluatorOption
+	var coverage *baccess.Coverage
+	if *cover || *coverHTML != "" {
+		coverage = baccess.NewCoverage()
+		opts = append(opts, baccess.WithCoverage(coverage))
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[Entity, Entity](), baccess.NewRegistry[Entity, Entity](), opts...)
+	if err != nil {
+		return fmt.Errorf("test: %w", err)
+	}
+
+	var total int
+	var failures []testFailure
+	for _, path := range flags.Args() {
+		suite, err := loadTestSuite(path)
+		if err != nil {
+			return fmt.Errorf("test: %w", err)
+		}
+
+		for i, tc := range suite.Tests {
+			name := tc.Name
+			if name == "" {
+				name = fmt.Sprintf("%s#%d", filepath.Base(path), i+1)
+			}
+
+			req := baccess.AccessRequest[Entity, Entity]{
+				Subject:  suite.Subjects[tc.Subject],
+				Resource: suite.Resources[tc.Resource],
+				Action:   tc.Action,
+			}
+			if tc.Environment != nil {
+				req.Environment = tc.Environment
+			}
+
+			decision := evaluator.Explain(req)
+			total++
+			if decision.Allowed != (tc.Expect == "allow") {
+				failures = append(failures, testFailure{name: name, test: tc, decision: decision})
+				if *verbose {
+					fmt.Fprintf(w, "FAIL %s\n", name)
+				}
+			} else if *verbose {
+				fmt.Fprintf(w, "ok   %s\n", name)
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		fmt.Fprintf(w, "ok: %d tests passed\n", total)
+	} else {
+		printFailures(w, failures)
+	}
+
+	if *cover {
+		fmt.Fprintln(w)
+		if err := coverage.WriteText(w); err != nil {
+			return fmt.Errorf("test: %w", err)
+		}
+	}
+	if *coverHTML != "" {
+		if err := writeCoverageHTML(*coverHTML, coverage); err != nil {
+			return fmt.Errorf("test: %w", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("test: %d of %d tests failed", len(failures), total)
+	}
+
+	return nil
+}
+
+func writeCoverageHTML(path string, coverage *baccess.Coverage) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write coverage report: %w", err)
+	}
+	if err := coverage.WriteHTML(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write coverage report: %w", err)
+	}
+
+	return file.Close()
+}
+
+// printFailures writes a table of the failed cases followed by the Explain
+// trace of each.
+func printFailures(w io.Writer, failures []testFailure) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TEST\tSUBJECT\tRESOURCE\tACTION\tWANT\tGOT")
+	for _, f := range failures {
+		got := "deny"
+		if f.decision.Allowed {
+			got = "allow"
+		}
+		resource := f.test.Resource
+		if resource == "" {
+			resource = "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", f.name, f.test.Subject, resource, f.test.Action, f.test.Expect, got)
+	}
+	table.Flush()
+
+	for _, f := range failures {
+		fmt.Fprintf(w, "\n%s:\n  %s\n", f.name, f.decision.Reason)
+		for _, trace := range f.decision.Policies {
+			fmt.Fprintf(w, "  %s\n", trace)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// loadTestSuite reads a test file, parsing it as YAML when the path ends in
+// ".yaml" or ".yml" and as JSON otherwise, and checks its references.
+func loadTestSuite(path string) (*testSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test file: %w", err)
+	}
+
+	// YAML is converted to JSON so that attributes have the same types as in
+	// requests to the decision point, e.g. float64 for every number.
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var suite testSuite
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&suite); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := suite.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &suite, nil
+}
+
+func (s *testSuite) validate() error {
+	if len(s.Tests) == 0 {
+		return errors.New("no tests defined")
+	}
+
+	for i, tc := range s.Tests {
+		if _, ok := s.Subjects[tc.Subject]; !ok {
+			return fmt.Errorf("tests[%d]: unknown subject %q", i, tc.Subject)
+		}
+		if _, ok := s.Resources[tc.Resource]; tc.Resource != "" && !ok {
+			return fmt.Errorf("tests[%d]: unknown resource %q", i, tc.Resource)
+		}
+		if tc.Action == "" {
+			return fmt.Errorf("tests[%d]: action is required", i)
+		}
+		if tc.Expect != "allow" && tc.Expect != "deny" {
+			return fmt.Errorf("tests[%d]: expect must be \"allow\" or \"deny\", got %q", i, tc.Expect)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func TestRunTest(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runTest([]string{"-config", "testdata/policy.yaml", "testdata/policy_test.yaml"}, &out))
+	assert.Equal(t, "ok: 5 tests passed\n", out.String())
+
+	failing := writeTestFile(t, "failing.json", `{
+		"subjects": {"alice": {"id": "u1", "roles": ["editor"], "department": "sales"}},
+		"resources": {"archived": {"owner": "u1", "status": "archived"}},
+		"tests": [
+			{"name": "editor edits archived", "subject": "alice", "resource": "archived", "action": "edit", "expect": "allow"},
+			{"subject": "alice", "action": "delete", "expect": "allow"},
+			{"subject": "alice", "action": "delete", "expect": "deny"}
+		]
+	}`)
+
+	out.Reset()
+	err := runTest([]string{"-config", "testdata/policy.yaml", failing}, &out)
+	assert.EqualError(t, err, "test: 2 of 3 tests failed")
+	assert.Equal(t, `TEST                   SUBJECT  RESOURCE  ACTION  WANT   GOT
+editor edits archived  alice    archived  edit    allow  deny
+failing.json#2         alice    -         delete  allow  deny
+
+editor edits archived:
+  denied by deny policy "edit:isArchived" for role "editor"
+  allow "*" (rule 1) role "admin"=false condition "*"=true -> false
+  deny "edit:isArchived" (rule 5) role "editor"=true condition "isArchived"=true -> true
+  allow "edit:isOwner" (rule 5) role "editor"=true condition "isOwner"=true -> true
+
+failing.json#2:
+  no allow policy for action "delete" is satisfied
+  allow "*" (rule 1) role "admin"=false condition "*"=true -> false
+
+`, out.String())
+}
+
+func TestRunTest_Coverage(t *testing.T) {
+	html := filepath.Join(t.TempDir(), "coverage.html")
+
+	var out bytes.Buffer
+	require.NoError(t, runTest([]string{"-config", "testdata/policy.yaml", "-cover", "-coverhtml", html, "testdata/policy_test.yaml"}, &out))
+	assert.Contains(t, out.String(), "policy coverage: 5 of 5 rules covered (100.0%)")
+	assert.Contains(t, out.String(), "viewer  allow   read:sameDepartment           1     0     1      never true\n")
+
+	data, err := os.ReadFile(html)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<code>read:sameDepartment</code>")
+}
+
+func TestRunTest_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown subject", "tests:\n  - {subject: bob, action: read, expect: allow}\n", `unknown subject "bob"`},
+		{"unknown resource", "subjects: {bob: {}}\ntests:\n  - {subject: bob, resource: doc, action: read, expect: allow}\n", `unknown resource "doc"`},
+		{"missing action", "subjects: {bob: {}}\ntests:\n  - {subject: bob, expect: allow}\n", "action is required"},
+		{"bad expectation", "subjects: {bob: {}}\ntests:\n  - {subject: bob, action: read, expect: yes}\n", `expect must be "allow" or "deny", got "yes"`},
+		{"unknown field", "subjects: {bob: {}}\ntests:\n  - {subject: bob, action: read, expected: allow}\n", `unknown field "expected"`},
+		{"no tests", "subjects: {bob: {}}\n", "no tests defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "tests.yaml", tt.content)
+			err := runTest([]string{"-config", "testdata/policy.yaml", path}, &bytes.Buffer{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	assert.EqualError(t, runTest([]string{"testdata/policy_test.yaml"}, &bytes.Buffer{}), "test: -config is required")
+	assert.EqualError(t, runTest([]string{"-config", "testdata/policy.yaml"}, &bytes.Buffer{}), "test: at least one test file is required")
+}
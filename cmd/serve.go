@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brian-nunez/baccess"
+)
+
+// runServe loads a policy file and serves decisions until interrupted,
+// reloading the file whenever it changes.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := flags.String("config", "", "policy file (JSON or YAML), required")
+	addr := flags.String("addr", ":8181", "address to listen on")
+	poll := flags.Duration("poll", 2*time.Second, "how often to check the policy file for changes")
+	maxBody := flags.Int64("max-body", 1<<20, "maximum request body size in bytes")
+	maxBatch := flags.Int("max-batch", 1000, "maximum number of requests per batch")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *configPath == "" {
+		return errors.New("serve: -config is required")
+	}
+
+	watcher, err := baccess.NewWatcher(
+		*configPath,
+		baccess.NewRBAC[Entity, Entity](),
+		baccess.NewRegistry[Entity, Entity](),
+		baccess.WithPollInterval(*poll),
+		baccess.WithReloadHook(func(event baccess.WatchEvent) {
+			if event.Err != nil {
+				log.Printf("policy reload failed, keeping the previous policy: %v", event.Err)
+			} else {
+				log.Printf("loaded policy from %s", event.Path)
+			}
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go watcher.Run(ctx)
+
+	s := &server{evaluator: watcher.Holder(), maxBody: *maxBody, maxBatch: *maxBatch}
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("policy decision point listening on %s", *addr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return httpServer.Shutdown(shutdown)
+}
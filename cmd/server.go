@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/brian-nunez/baccess"
+	"github.com/brian-nunez/baccess/httpauth"
+)
+
+type evaluateRequest struct {
+	Subject     Entity              `json:"subject"`
+	Resource    Entity              `json:"resource"`
+	Action      string              `json:"action"`
+	Environment baccess.Environment `json:"environment,omitempty"`
+	// Explain adds the reason and the evaluated policies to the response.
+	Explain bool `json:"explain,omitempty"`
+}
+
+type evaluateResponse struct {
+	Allowed  bool     `json:"allowed"`
+	Reason   string   `json:"reason,omitempty"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+type batchRequest struct {
+	Requests []evaluateRequest `json:"requests"`
+}
+
+type batchResponse struct {
+	Results []evaluateResponse `json:"results"`
+}
+
+type allowedActionsRequest struct {
+	Subject  Entity `json:"subject"`
+	Resource Entity `json:"resource"`
+}
+
+type allowedActionsResponse struct {
+	Actions []string `json:"actions"`
+	// Everything is true when wildcard policies grant actions that no
+	// policy names.
+	Everything bool `json:"everything"`
+}
+
+// server is a policy decision point: it answers authorization questions for
+// other services over HTTP/JSON.
+type server struct {
+	evaluator *baccess.EvaluatorHolder[Entity, Entity]
+	maxBody   int64
+	maxBatch  int
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/evaluate", s.handleEvaluate)
+	mux.HandleFunc("POST /v1/evaluate/batch", s.handleBatch)
+	mux.HandleFunc("POST /v1/allowed-actions", s.handleAllowedActions)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+
+	return mux
+}
+
+func (s *server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	var req evaluateRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		httpauth.WriteProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, s.evaluate(req))
+}
+
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var batch batchRequest
+	if !s.decode(w, r, &batch) {
+		return
+	}
+	if len(batch.Requests) > s.maxBatch {
+		httpauth.WriteProblem(w, http.StatusBadRequest, fmt.Sprintf("at most %d requests are allowed per batch", s.maxBatch))
+		return
+	}
+	for i, req := range batch.Requests {
+		if err := req.validate(); err != nil {
+			httpauth.WriteProblem(w, http.StatusBadRequest, fmt.Sprintf("requests[%d]: %v", i, err))
+			return
+		}
+	}
+
+	results := make([]evaluateResponse, len(batch.Requests))
+	for i, req := range batch.Requests {
+		results[i] = s.evaluate(req)
+	}
+
+	writeJSON(w, batchResponse{Results: results})
+}
+
+func (s *server) handleAllowedActions(w http.ResponseWriter, r *http.Request) {
+	var req allowedActionsRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	actions, everything := s.evaluator.AllowedActions(req.Subject, req.Resource)
+	if actions == nil {
+		actions = []string{}
+	}
+
+	writeJSON(w, allowedActionsResponse{Actions: actions, Everything: everything})
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.evaluator.Load() == nil {
+		httpauth.WriteProblem(w, http.StatusServiceUnavailable, "no policy loaded")
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (req evaluateRequest) validate() error {
+	if req.Action == "" {
+		return errors.New("action is required")
+	}
+
+	return nil
+}
+
+func (s *server) evaluate(req evaluateRequest) evaluateResponse {
+	access := baccess.AccessRequest[Entity, Entity]{
+		Subject:  req.Subject,
+		Resource: req.Resource,
+		Action:   req.Action,
+	}
+	if req.Environment != nil {
+		access.Environment = req.Environment
+	}
+
+	if !req.Explain {
+		return evaluateResponse{Allowed: s.evaluator.Evaluate(access)}
+	}
+
+	decision := s.evaluator.Explain(access)
+	resp := evaluateResponse{Allowed: decision.Allowed, Reason: decision.Reason}
+	for _, trace := range decision.Policies {
+		resp.Policies = append(resp.Policies, trace.String())
+	}
+
+	return resp
+}
+
+// decode reads a JSON body into v, answering 400 when it is malformed.
+func (s *server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpauth.WriteProblem(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		httpauth.WriteProblem(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	cfg, err := baccess.LoadConfigFromFile("testdata/policy.yaml")
+	require.NoError(t, err)
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[Entity, Entity](), baccess.NewRegistry[Entity, Entity]())
+	require.NoError(t, err)
+
+	s := &server{evaluator: baccess.NewEvaluatorHolder(evaluator), maxBody: 4096, maxBatch: 3}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// post sends body to path and decodes the JSON response into a map.
+func post(t *testing.T, ts *httptest.Server, path, body string) (int, map[string]any) {
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+
+	return resp.StatusCode, decoded
+}
+
+func TestServer_Evaluate(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{
+			"condition over attributes",
+			`{"subject": {"id": "u1", "roles": ["viewer"], "department": "sales"}, "resource": {"department": "sales"}, "action": "read"}`,
+			true,
+		},
+		{
+			"condition fails",
+			`{"subject": {"id": "u1", "roles": ["viewer"], "department": "sales"}, "resource": {"department": "hr"}, "action": "read"}`,
+			false,
+		},
+		{
+			"inherited role",
+			`{"subject": {"id": "u1", "roles": ["editor"], "department": "sales"}, "resource": {"department": "sales"}, "action": "read"}`,
+			true,
+		},
+		{
+			"owner edits",
+			`{"subject": {"id": "u1", "roles": ["editor"]}, "resource": {"owner": "u1"}, "action": "edit"}`,
+			true,
+		},
+		{
+			"deny rule",
+			`{"subject": {"id": "u1", "roles": ["editor"]}, "resource": {"owner": "u1", "status": "archived"}, "action": "edit"}`,
+			false,
+		},
+		{
+			"environment",
+			`{"subject": {"id": "u1", "roles": ["editor"]}, "resource": {"owner": "u1"}, "action": "share", "environment": {"network": "corp"}}`,
+			true,
+		},
+		{
+			"missing environment",
+			`{"subject": {"id": "u1", "roles": ["editor"]}, "resource": {"owner": "u1"}, "action": "share"}`,
+			false,
+		},
+		{
+			"no roles",
+			`{"subject": {"id": "u1"}, "resource": {}, "action": "read"}`,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := post(t, ts, "/v1/evaluate", tt.body)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]any{"allowed": tt.want}, resp)
+		})
+	}
+
+	status, resp := post(t, ts, "/v1/evaluate", `{"subject": {"id": "u1", "roles": ["editor"]}, "resource": {"owner": "u1", "status": "archived"}, "action": "edit", "explain": true}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, false, resp["allowed"])
+	assert.Equal(t, `denied by deny policy "edit:isArchived" for role "editor"`, resp["reason"])
+	assert.Len(t, resp["policies"], 3)
+}
+
+func TestServer_Batch(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, resp := post(t, ts, "/v1/evaluate/batch", `{"requests": [
+		{"subject": {"id": "u1", "roles": ["admin"]}, "resource": {}, "action": "delete"},
+		{"subject": {"id": "u1", "roles": ["viewer"]}, "resource": {}, "action": "delete"}
+	]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"results": []any{
+		map[string]any{"allowed": true},
+		map[string]any{"allowed": false},
+	}}, resp)
+
+	status, resp = post(t, ts, "/v1/evaluate/batch", `{"requests": [{"action": "read"}, {"subject": {}}]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "requests[1]: action is required", resp["detail"])
+
+	status, resp = post(t, ts, "/v1/evaluate/batch", `{"requests": [{"action": "a"}, {"action": "b"}, {"action": "c"}, {"action": "d"}]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "at most 3 requests are allowed per batch", resp["detail"])
+}
+
+func TestServer_AllowedActions(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, resp := post(t, ts, "/v1/allowed-actions", `{"subject": {"id": "u1", "roles": ["editor"], "department": "sales"}, "resource": {"owner": "u1", "department": "sales"}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"actions": []any{"edit", "read"}, "everything": false}, resp)
+
+	status, resp = post(t, ts, "/v1/allowed-actions", `{"subject": {"id": "u2"}, "resource": {}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"actions": []any{}, "everything": false}, resp)
+
+	status, resp = post(t, ts, "/v1/allowed-actions", `{"subject": {"roles": ["admin"]}, "resource": {}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, resp["everything"])
+}
+
+func TestServer_BadRequests(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantDetail string
+	}{
+		{"missing action", `{"subject": {}, "resource": {}}`, http.StatusBadRequest, "action is required"},
+		{"unknown field", `{"subject": {}, "action": "read", "actions": []}`, http.StatusBadRequest, `invalid request body: json: unknown field "actions"`},
+		{"malformed", `{"subject": `, http.StatusBadRequest, "invalid request body: unexpected EOF"},
+		{"too large", `{"action": "` + strings.Repeat("x", 5000) + `"}`, http.StatusRequestEntityTooLarge, "request body exceeds 4096 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := post(t, ts, "/v1/evaluate", tt.body)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantDetail, resp["detail"])
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/evaluate")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestEntity(t *testing.T) {
+	var e Entity
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "u1", "roles": ["admin", 3, "viewer"], "team": {"name": "core"}}`), &e))
+
+	assert.Equal(t, "u1", e.GetID())
+	assert.Equal(t, []string{"admin", "viewer"}, e.GetRoles())
+	assert.Equal(t, map[string]any{"name": "core"}, e.GetAttribute("team"))
+	assert.Nil(t, e.GetAttribute("missing"))
+	assert.Empty(t, Entity{}.GetRoles())
+}
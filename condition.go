@@ -0,0 +1,315 @@
+package baccess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A rule condition is either "*" or a boolean expression over predicate
+// names:
+//
+//	condition := or
+//	or        := and { "|" and }
+//	and       := unary { "&" unary }
+//	unary     := "!" unary | primary
+//	primary   := name [ "(" [ arg { "," arg } ] ")" ] | "(" or ")"
+//	arg       := 'quoted' | "quoted" | bare
+//
+// so "isOwner|isCollaborator", "isOwner&!isArchived",
+// "(isOwner|isCollaborator)&!isArchived" and "levelAtLeast(3)" are all valid
+// conditions. A name followed by arguments is built by a PredicateFactory.
+
+// ConditionError reports a syntax error in a rule condition.
+type ConditionError struct {
+	Condition string
+	// Column is the 1-based position of the error within Condition.
+	Column  int
+	Message string
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("invalid condition '%s': column %d: %s", e.Condition, e.Column, e.Message)
+}
+
+type conditionExpr interface {
+	// leaves appends every predicate referenced by the expression.
+	leaves(dst []conditionRef) []conditionRef
+}
+
+// conditionRef is a predicate reference, either a plain name or a factory
+// call such as attrEquals(department,finance).
+type conditionRef struct {
+	name string
+	call bool
+	args []string
+}
+
+func (c conditionRef) String() string {
+	if !c.call {
+		return c.name
+	}
+
+	return c.name + "(" + strings.Join(c.args, ",") + ")"
+}
+
+type conditionNot struct {
+	operand conditionExpr
+}
+
+type conditionAnd struct {
+	left, right conditionExpr
+}
+
+type conditionOr struct {
+	left, right conditionExpr
+}
+
+func (c conditionRef) leaves(dst []conditionRef) []conditionRef { return append(dst, c) }
+func (c conditionNot) leaves(dst []conditionRef) []conditionRef { return c.operand.leaves(dst) }
+func (c conditionAnd) leaves(dst []conditionRef) []conditionRef {
+	return c.right.leaves(c.left.leaves(dst))
+}
+func (c conditionOr) leaves(dst []conditionRef) []conditionRef {
+	return c.right.leaves(c.left.leaves(dst))
+}
+
+type conditionParser struct {
+	input string
+	pos   int
+}
+
+// parseCondition parses a rule condition into an expression tree.
+func parseCondition(condition string) (conditionExpr, error) {
+	p := &conditionParser{input: condition}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected '%c'", p.input[p.pos])
+	}
+
+	return expr, nil
+}
+
+// parseRuleCondition parses the condition of a rule. Rules named a single
+// predicate before conditions became expressions, so a condition without
+// operators that known reports as a predicate name, such as "team:admin" or
+// "is owner", is kept as written rather than parsed.
+func parseRuleCondition(condition string, known func(name string) bool) (conditionExpr, error) {
+	// A valid name parses to itself, so only other names need the lookup.
+	literal := !strings.ContainsAny(condition, "|&!()") && !validConditionName(strings.TrimSpace(condition))
+	if literal && known(condition) {
+		return conditionRef{name: condition}, nil
+	}
+
+	return parseCondition(condition)
+}
+
+func (p *conditionParser) errorf(format string, args ...any) error {
+	return &ConditionError{Condition: p.input, Column: p.pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *conditionParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept consumes c if it is the next non-space character.
+func (p *conditionParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *conditionParser) parseOr() (conditionExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept('|') {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionOr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept('&') {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionAnd{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (conditionExpr, error) {
+	if p.accept('!') {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return conditionNot{operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (conditionExpr, error) {
+	if p.accept('(') {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, p.errorf("expected ')'")
+		}
+		return expr, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && isConditionNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos == len(p.input) {
+			return nil, p.errorf("expected predicate name, found end of condition")
+		}
+		return nil, p.errorf("expected predicate name, found '%c'", p.input[p.pos])
+	}
+
+	name := conditionRef{name: p.input[start:p.pos]}
+	if !p.accept('(') {
+		return name, nil
+	}
+
+	name.call = true
+	if p.accept(')') {
+		return name, nil
+	}
+	for {
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		name.args = append(name.args, arg)
+
+		if p.accept(')') {
+			return name, nil
+		}
+		if !p.accept(',') {
+			if p.pos == len(p.input) {
+				return nil, p.errorf("expected ',' or ')', found end of condition")
+			}
+			return nil, p.errorf("expected ',' or ')', found '%c'", p.input[p.pos])
+		}
+	}
+}
+
+// parseArg parses a factory argument: a quoted string, or a bare value that
+// runs up to the next ',' or ')' with surrounding spaces removed.
+func (p *conditionParser) parseArg() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.input) && (p.input[p.pos] == '\'' || p.input[p.pos] == '"') {
+		quote := p.input[p.pos]
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end == -1 {
+			return "", p.errorf("unterminated quoted argument")
+		}
+		arg := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return arg, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(",()'\"", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	arg := strings.TrimSpace(p.input[start:p.pos])
+	if arg == "" {
+		if p.pos == len(p.input) {
+			return "", p.errorf("expected argument, found end of condition")
+		}
+		return "", p.errorf("expected argument, found '%c'", p.input[p.pos])
+	}
+
+	return arg, nil
+}
+
+func isConditionNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.'
+}
+
+// validConditionName reports whether name can be referenced from a rule.
+func validConditionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isConditionNameChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resolveCondition looks up a single predicate reference. Factory calls need
+// a provider that implements FactoryProvider.
+func resolveCondition[S any, R any](
+	provider PredicateProvider[S, R],
+	leaf conditionRef,
+) (Predicate[AccessRequest[S, R]], error) {
+	if !leaf.call {
+		return provider.GetPredicate(leaf.name)
+	}
+
+	factories, ok := provider.(FactoryProvider[S, R])
+	if !ok {
+		return nil, fmt.Errorf("provider %T does not support predicate factories", provider)
+	}
+
+	return factories.BuildPredicate(leaf.name, leaf.args)
+}
+
+// compileCondition composes expr with Predicate.And/Or/Not, resolving each
+// predicate reference through lookup.
+func compileCondition[S any, R any](
+	expr conditionExpr,
+	lookup func(leaf conditionRef) Predicate[AccessRequest[S, R]],
+) Predicate[AccessRequest[S, R]] {
+	switch e := expr.(type) {
+	case conditionNot:
+		return compileCondition(e.operand, lookup).Not()
+	case conditionAnd:
+		return compileCondition(e.left, lookup).And(compileCondition(e.right, lookup))
+	case conditionOr:
+		return compileCondition(e.left, lookup).Or(compileCondition(e.right, lookup))
+	case conditionRef:
+		return lookup(e)
+	}
+
+	panic(fmt.Sprintf("baccess: unexpected condition node %T", expr))
+}
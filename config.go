@@ -4,24 +4,183 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
 type RolePolicyConfig struct {
-	Allow []string `json:"allow"`
+	Allow []string `json:"allow" yaml:"allow"`
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`
 }
 
 type Config struct {
-	Policies map[string]RolePolicyConfig `json:"policies"`
+	Policies map[string]RolePolicyConfig `json:"policies" yaml:"policies"`
+	// Inherits maps a role to the roles whose permissions it inherits.
+	Inherits map[string][]string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+	// Algorithm selects how allow and deny rules are combined (default deny-overrides).
+	Algorithm CombiningAlgorithm `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// Matcher selects how rule actions are matched against requested actions
+	// (default "default"); an explicit WithActionMatcher takes precedence.
+	Matcher ActionMatching `json:"matcher,omitempty" yaml:"matcher,omitempty"`
+	// Conditions defines named inline expressions (see CompileExpression)
+	// that rules can reference like any other predicate name.
+	Conditions map[string]string `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 }
 
+// ConfigFormat identifies the encoding of a policy document.
+type ConfigFormat string
+
+const (
+	FormatJSON ConfigFormat = "json"
+	FormatYAML ConfigFormat = "yaml"
+)
+
+// formatFromPath picks YAML for ".yaml"/".yml" files and JSON otherwise.
+func formatFromPath(path string) ConfigFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// LoadConfigFromFile loads a config file, parsing it as YAML when the path
+// ends in ".yaml" or ".yml" and as JSON otherwise.
 func LoadConfigFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return parseConfigFile(path, data)
+}
+
+func parseConfigFile(path string, data []byte) (*Config, error) {
+	return parseConfigFormat(data, formatFromPath(path))
+}
+
+func parseConfigFormat(data []byte, format ConfigFormat) (*Config, error) {
+	switch format {
+	case FormatJSON:
+		return parseConfig(data)
+	case FormatYAML:
+		return LoadConfigFromYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported config format '%s'", format)
+	}
+}
+
+// LoadConfig reads a policy document in the given format from r, e.g. a
+// file embedded with //go:embed or an object storage download.
+func LoadConfig(r io.Reader, format ConfigFormat) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return parseConfigFormat(data, format)
+}
+
+// LoadConfigFS loads every file in fsys matching pattern (e.g.
+// "policies/*.json") and merges them into a single Config. The format of each
+// file is chosen by its extension. A role, inheritance entry, named condition
+// or combining algorithm defined by more than one file is a conflict; every
+// conflict is reported with the files involved.
+func LoadConfigFS(fsys fs.FS, pattern string) (*Config, error) {
+	paths, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config pattern '%s': %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files match pattern '%s'", pattern)
+	}
+
+	merged := &Config{
+		Policies: make(map[string]RolePolicyConfig),
+		Inherits: make(map[string][]string),
+	}
+	policyOrigin := make(map[string]string)
+	inheritsOrigin := make(map[string]string)
+	conditionOrigin := make(map[string]string)
+	algorithmOrigin := ""
+	matcherOrigin := ""
+	var errs error
+
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: failed to read config file: %w", path, err))
+			continue
+		}
+		cfg, err := parseConfigFile(path, data)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		for _, role := range slices.Sorted(maps.Keys(cfg.Policies)) {
+			if origin, ok := policyOrigin[role]; ok {
+				errs = errors.Join(errs, fmt.Errorf("role '%s' is defined in both %s and %s", role, origin, path))
+				continue
+			}
+			policyOrigin[role] = path
+			merged.Policies[role] = cfg.Policies[role]
+		}
+
+		for _, role := range slices.Sorted(maps.Keys(cfg.Inherits)) {
+			if origin, ok := inheritsOrigin[role]; ok {
+				errs = errors.Join(errs, fmt.Errorf("inheritance of role '%s' is defined in both %s and %s", role, origin, path))
+				continue
+			}
+			inheritsOrigin[role] = path
+			merged.Inherits[role] = cfg.Inherits[role]
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(cfg.Conditions)) {
+			if origin, ok := conditionOrigin[name]; ok {
+				errs = errors.Join(errs, fmt.Errorf("condition '%s' is defined in both %s and %s", name, origin, path))
+				continue
+			}
+			if merged.Conditions == nil {
+				merged.Conditions = make(map[string]string)
+			}
+			conditionOrigin[name] = path
+			merged.Conditions[name] = cfg.Conditions[name]
+		}
+
+		if cfg.Algorithm != "" {
+			if algorithmOrigin != "" && cfg.Algorithm != merged.Algorithm {
+				errs = errors.Join(errs, fmt.Errorf("combining algorithm '%s' in %s conflicts with '%s' in %s", cfg.Algorithm, path, merged.Algorithm, algorithmOrigin))
+			} else if algorithmOrigin == "" {
+				algorithmOrigin = path
+				merged.Algorithm = cfg.Algorithm
+			}
+		}
+
+		if cfg.Matcher != "" {
+			if matcherOrigin != "" && cfg.Matcher != merged.Matcher {
+				errs = errors.Join(errs, fmt.Errorf("action matcher '%s' in %s conflicts with '%s' in %s", cfg.Matcher, path, merged.Matcher, matcherOrigin))
+			} else if matcherOrigin == "" {
+				matcherOrigin = path
+				merged.Matcher = cfg.Matcher
+			}
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return merged, nil
+}
+
+func parseConfig(data []byte) (*Config, error) {
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
@@ -48,60 +207,222 @@ type PredicateProvider[S any, R any] interface {
 	GetPredicate(name string) (Predicate[AccessRequest[S, R]], error)
 }
 
+// conditionProvider resolves the named expressions of a Config before
+// falling back to the caller's provider.
+type conditionProvider[S any, R any] struct {
+	conditions map[string]Predicate[AccessRequest[S, R]]
+	// broken holds the names of conditions that failed to compile.
+	broken map[string]bool
+	next   PredicateProvider[S, R]
+}
+
+// compileConditions compiles cfg.Conditions and returns a provider that
+// serves them ahead of next, along with every compile error.
+func compileConditions[S any, R any](cfg *Config, next PredicateProvider[S, R]) (*conditionProvider[S, R], error) {
+	provider := &conditionProvider[S, R]{
+		conditions: make(map[string]Predicate[AccessRequest[S, R]], len(cfg.Conditions)),
+		broken:     make(map[string]bool),
+		next:       next,
+	}
+
+	var errs error
+	for _, name := range slices.Sorted(maps.Keys(cfg.Conditions)) {
+		pred, err := CompileExpression[S, R](cfg.Conditions[name])
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("condition '%s': %w", name, err))
+			provider.broken[name] = true
+			continue
+		}
+		provider.conditions[name] = pred
+	}
+
+	return provider, errs
+}
+
+func (p *conditionProvider[S, R]) GetPredicate(name string) (Predicate[AccessRequest[S, R]], error) {
+	if pred, ok := p.conditions[name]; ok {
+		return pred, nil
+	}
+	if p.broken[name] {
+		return nil, fmt.Errorf("condition '%s' does not compile", name)
+	}
+	if p.next == nil {
+		return nil, fmt.Errorf("predicate not found: %s", name)
+	}
+
+	return p.next.GetPredicate(name)
+}
+
+func (p *conditionProvider[S, R]) BuildPredicate(name string, args []string) (Predicate[AccessRequest[S, R]], error) {
+	factories, ok := p.next.(FactoryProvider[S, R])
+	if !ok {
+		return nil, fmt.Errorf("provider %T does not support predicate factories", p.next)
+	}
+
+	return factories.BuildPredicate(name, args)
+}
+
+// GetPartial serves the partial forms of the wrapped provider. Named
+// conditions have none.
+func (p *conditionProvider[S, R]) GetPartial(name string) (PartialPredicate[S, R], error) {
+	if _, ok := p.conditions[name]; ok || p.broken[name] {
+		return PartialPredicate[S, R]{}, fmt.Errorf("condition '%s' cannot be partially evaluated", name)
+	}
+	partials, ok := p.next.(PartialProvider[S, R])
+	if !ok {
+		return PartialPredicate[S, R]{}, fmt.Errorf("provider %T does not support partial evaluation", p.next)
+	}
+
+	return partials.GetPartial(name)
+}
+
+// FactoryProvider is implemented by providers that can build predicates from
+// arguments, as referenced by conditions like "levelAtLeast(3)".
+type FactoryProvider[S any, R any] interface {
+	BuildPredicate(name string, args []string) (Predicate[AccessRequest[S, R]], error)
+}
+
 func BuildEvaluator[S RoleBearer, R any](
 	cfg *Config,
 	rbac *RBAC[S, R],
 	provider PredicateProvider[S, R],
+	opts ...EvaluatorOption,
 ) (*Evaluator[S, R], error) {
-	evaluator := NewEvaluator[S, R]()
 	var errs error
 
+	// A nil RBAC is an RBAC without inheritance.
+	if rbac == nil {
+		rbac = NewRBAC[S, R]()
+	}
+	if cfg.Algorithm != "" {
+		if !cfg.Algorithm.valid() {
+			errs = errors.Join(errs, fmt.Errorf("unknown combining algorithm '%s'", cfg.Algorithm))
+		} else {
+			opts = append([]EvaluatorOption{WithCombiningAlgorithm(cfg.Algorithm)}, opts...)
+		}
+	}
+	if cfg.Matcher != "" {
+		if matcher := cfg.Matcher.matcher(); matcher == nil {
+			errs = errors.Join(errs, fmt.Errorf("unknown action matcher '%s'", cfg.Matcher))
+		} else {
+			opts = append([]EvaluatorOption{WithActionMatcher(matcher)}, opts...)
+		}
+	}
+	evaluator := NewEvaluator[S, R](opts...)
+
+	var options evaluatorOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if len(cfg.Inherits) > 0 {
+		rbac = rbac.clone()
+		for role, inherited := range cfg.Inherits {
+			rbac.Inherit(role, inherited...)
+		}
+	}
+	if err := rbac.Validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	if len(cfg.Conditions) > 0 {
+		conditions, err := compileConditions(cfg, provider)
+		errs = errors.Join(errs, err)
+		provider = conditions
+	}
+
 	alwaysTrue := func(req AccessRequest[S, R]) bool { return true }
+	known := func(name string) bool {
+		_, err := provider.GetPredicate(name)
+		return err == nil
+	}
 
-	for role, policy := range cfg.Policies {
-		for _, allowRule := range policy.Allow {
-			// Parse "action:condition" or just "action" (implying always)
-			parts := strings.SplitN(allowRule, ":", 2)
-			action := parts[0]
-			var conditionName string
-
-			if len(parts) > 1 {
-				conditionName = parts[1]
-			} else {
-				// If no condition specified, assume "*" (Always)
-				conditionName = "*"
-			}
+	addRule := func(role string, rule string, effect Effect) {
+		// Parse "action:condition" or just "action" (implying always)
+		parts := strings.SplitN(rule, ":", 2)
+		action := parts[0]
+		var conditionName string
 
-			var conditionPred Predicate[AccessRequest[S, R]]
+		if len(parts) > 1 {
+			conditionName = parts[1]
+		} else {
+			// If no condition specified, assume "*" (Always)
+			conditionName = "*"
+		}
+
+		var conditionPred Predicate[AccessRequest[S, R]]
 
-			if conditionName == "*" {
-				conditionPred = alwaysTrue
-			} else {
-				p, err := provider.GetPredicate(conditionName)
+		// Fail closed: a broken allow never grants, a broken deny always denies.
+		failClosed := Deny[S, R]()
+		if effect == EffectDeny {
+			failClosed = Allow[S, R]()
+		}
+
+		var conditionPartial func(AccessRequest[S, R]) Residual
+
+		if conditionName == "*" {
+			conditionPred = alwaysTrue
+			conditionPartial = func(AccessRequest[S, R]) Residual { return residualTrue }
+		} else if expr, err := parseRuleCondition(conditionName, known); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("role '%s': rule '%s': %w", role, rule, err))
+			conditionPred = failClosed
+		} else {
+			failed := false
+			conditionPred = compileCondition(expr, func(leaf conditionRef) Predicate[AccessRequest[S, R]] {
+				p, err := resolveCondition(provider, leaf)
 				if err != nil {
-					errs = errors.Join(errs, fmt.Errorf("role '%s': rule '%s': failed to get predicate '%s': %w", role, allowRule, conditionName, err))
-					conditionPred = Deny[S, R]()
-				} else {
-					conditionPred = p
+					errs = errors.Join(errs, fmt.Errorf("role '%s': rule '%s': failed to get predicate '%s': %w", role, rule, leaf, err))
+					failed = true
+					return Deny[S, R]()
 				}
+				return p
+			})
+			if failed {
+				conditionPred = failClosed
+			} else if partials, ok := provider.(PartialProvider[S, R]); ok {
+				conditionPartial = compilePartialCondition(expr, func(leaf conditionRef) func(AccessRequest[S, R]) Residual {
+					if leaf.call {
+						return nil
+					}
+					p, err := partials.GetPartial(leaf.name)
+					if err != nil {
+						return nil
+					}
+					return p.Partial
+				})
 			}
+		}
 
-			// Combine: Subject has Role AND Condition is Met
-			// Use RBAC to check role (supporting hierarchy)
-			rolePred := rbac.HasRole(role)
-			fullPred := rolePred.And(conditionPred)
-
-			// Register policy
-			// The key for the policy map should be the full action rule if it contains a condition,
-			// otherwise just the action.
-			// Register policy
-			policyKey := action
-			if allowRule == "*" || (action == "*" && conditionName == "*") {
-				policyKey = "*"
-			} else if len(parts) > 1 {
-				policyKey = allowRule
-			}
-			evaluator.AddPolicy(policyKey, fullPred)
+		// Combine: Subject has Role AND Condition is Met
+		// Use RBAC to check role (supporting hierarchy)
+		rolePred := rbac.HasRole(role)
+
+		if options.coverage != nil {
+			counter := options.coverage.rule(role, rule, effect, conditionName)
+			conditionPred = instrumentCondition(counter, rolePred, conditionPred)
+		}
+
+		// The key for the policy should be the full action rule if it contains a condition,
+		// otherwise just the action.
+		policyKey := action
+		if rule == "*" || (action == "*" && conditionName == "*") {
+			policyKey = "*"
+		} else if len(parts) > 1 {
+			policyKey = rule
+		}
+
+		evaluator.addRolePolicy(policyKey, effect, role, rolePred, conditionName, conditionPred, conditionPartial)
+	}
+
+	// Roles are visited in name order, each role's deny rules before its allow
+	// rules, so that FirstApplicable sees a deterministic policy order.
+	for _, role := range slices.Sorted(maps.Keys(cfg.Policies)) {
+		policy := cfg.Policies[role]
+		for _, denyRule := range policy.Deny {
+			addRule(role, denyRule, EffectDeny)
+		}
+		for _, allowRule := range policy.Allow {
+			addRule(role, allowRule, EffectAllow)
 		}
 	}
 
@@ -2,12 +2,19 @@ package baccess_test
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
 	"github.com/brian-nunez/baccess"
 	auth_test_utils "github.com/brian-nunez/baccess/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type MockPredicateProvider struct {
@@ -266,3 +273,469 @@ func TestBuildEvaluator(t *testing.T) {
 	}
 	assert.False(t, evaluator5.Evaluate(printerReqWrongAction))
 }
+
+func TestBuildEvaluator_Inherits(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+
+	cfg, err := baccess.LoadConfigFromMap(map[string]any{
+		"policies": map[string]any{
+			"admin":  map[string]any{"allow": []string{"delete"}},
+			"editor": map[string]any{"allow": []string{"write"}},
+			"viewer": map[string]any{"allow": []string{"read"}},
+		},
+		"inherits": map[string]any{
+			"admin":  []string{"editor"},
+			"editor": []string{"viewer"},
+		},
+	})
+	assert.NoError(t, err)
+
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.NoError(t, err)
+
+	admin := auth_test_utils.MockSubject{ID: "a", Roles: []string{"admin"}}
+	viewer := auth_test_utils.MockSubject{ID: "v", Roles: []string{"viewer"}}
+	req := func(s auth_test_utils.MockSubject, action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: s, Action: action}
+	}
+
+	assert.True(t, evaluator.Evaluate(req(admin, "read")))
+	assert.True(t, evaluator.Evaluate(req(admin, "write")))
+	assert.True(t, evaluator.Evaluate(req(admin, "delete")))
+	assert.True(t, evaluator.Evaluate(req(viewer, "read")))
+	assert.False(t, evaluator.Evaluate(req(viewer, "write")))
+
+	// The caller's RBAC is left untouched.
+	assert.False(t, rbac.HasRole("viewer").IsSatisfiedBy(req(admin, "read")))
+
+	cyclic := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"a": {Allow: []string{"read"}}},
+		Inherits: map[string][]string{"a": {"b"}, "b": {"a"}},
+	}
+	_, err = baccess.BuildEvaluator(cyclic, rbac, provider)
+	assert.ErrorContains(t, err, "role inheritance cycle: a -> b -> a")
+
+	// A nil RBAC has no inheritance of its own.
+	evaluator, err = baccess.BuildEvaluator(cfg, nil, provider)
+	require.NoError(t, err)
+	assert.True(t, evaluator.Evaluate(req(admin, "read")))
+	evaluator, err = baccess.BuildEvaluator(&baccess.Config{Policies: cfg.Policies}, nil, provider)
+	require.NoError(t, err)
+	assert.False(t, evaluator.Evaluate(req(admin, "read")))
+}
+
+func TestBuildEvaluator_Deny(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": func(req baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]) bool {
+				return req.Subject.ID == req.Resource.OwnerID
+			},
+		},
+	}
+
+	cfg, err := baccess.LoadConfigFromMap(map[string]any{
+		"policies": map[string]any{
+			"staff":      map[string]any{"allow": []string{"*"}},
+			"contractor": map[string]any{"deny": []string{"delete", "publish:isOwner"}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"delete", "publish:isOwner"}, cfg.Policies["contractor"].Deny)
+
+	employee := auth_test_utils.MockSubject{ID: "e", Roles: []string{"staff"}}
+	contractor := auth_test_utils.MockSubject{ID: "c", Roles: []string{"staff", "contractor"}}
+	req := func(s auth_test_utils.MockSubject, ownerID string, action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+			Subject:  s,
+			Resource: auth_test_utils.MockResource{OwnerID: ownerID},
+			Action:   action,
+		}
+	}
+
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.NoError(t, err)
+	assert.True(t, evaluator.Evaluate(req(employee, "x", "delete")))
+	assert.False(t, evaluator.Evaluate(req(contractor, "x", "delete")))
+	assert.False(t, evaluator.Evaluate(req(contractor, "x", "delete:isOwner")))
+	assert.True(t, evaluator.Evaluate(req(contractor, "x", "read")))
+	assert.True(t, evaluator.Evaluate(req(contractor, "x", "publish")))
+	assert.False(t, evaluator.Evaluate(req(contractor, "c", "publish")))
+
+	cfg.Algorithm = baccess.PermitOverrides
+	permissive, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, baccess.PermitOverrides, permissive.Algorithm())
+	assert.True(t, permissive.Evaluate(req(contractor, "x", "delete")))
+
+	// Options passed to BuildEvaluator take precedence over the config.
+	overridden, err := baccess.BuildEvaluator(cfg, rbac, provider, baccess.WithCombiningAlgorithm(baccess.DenyOverrides))
+	assert.NoError(t, err)
+	assert.False(t, overridden.Evaluate(req(contractor, "x", "delete")))
+
+	cfg.Algorithm = "most-permissive"
+	_, err = baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.ErrorContains(t, err, "unknown combining algorithm 'most-permissive'")
+
+	// A deny rule whose predicate cannot be resolved fails closed.
+	broken := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"staff": {Allow: []string{"read"}, Deny: []string{"read:missing"}},
+		},
+	}
+	brokenEvaluator, err := baccess.BuildEvaluator(broken, rbac, provider)
+	assert.Error(t, err)
+	assert.False(t, brokenEvaluator.Evaluate(req(employee, "x", "read")))
+}
+
+func TestLoadConfigFromYAML(t *testing.T) {
+	cfg, err := baccess.LoadConfigFromYAML([]byte(`
+algorithm: deny-overrides
+policies:
+  admin:
+    allow: ["*"]
+  editor:
+    allow:
+      - read
+      - delete:isOwner
+    deny:
+      - publish
+inherits:
+  admin: [editor]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, baccess.DenyOverrides, cfg.Algorithm)
+	assert.Equal(t, []string{"*"}, cfg.Policies["admin"].Allow)
+	assert.Equal(t, []string{"read", "delete:isOwner"}, cfg.Policies["editor"].Allow)
+	assert.Equal(t, []string{"publish"}, cfg.Policies["editor"].Deny)
+	assert.Equal(t, map[string][]string{"admin": {"editor"}}, cfg.Inherits)
+
+	_, err = baccess.LoadConfigFromYAML([]byte(`
+policies:
+  editor:
+    allow:
+      - read
+  viewer:
+    allow: read
+`))
+	assert.ErrorContains(t, err, "failed to parse config YAML: line 7, column 12")
+
+	_, err = baccess.LoadConfigFromYAML([]byte(`
+policies:
+  editor:
+    allow: [read, {action: write}]
+`))
+	assert.ErrorContains(t, err, "line 4, column 19")
+
+	_, err = baccess.LoadConfigFromYAML([]byte("policies: [\n"))
+	assert.ErrorContains(t, err, "failed to parse config YAML")
+}
+
+func TestLoadConfigFromFile_YAML(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"policies.yaml", "policies.YML"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("policies:\n  viewer:\n    allow: [read]\n"), 0o644))
+
+		cfg, err := baccess.LoadConfigFromFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"read"}, cfg.Policies["viewer"].Allow)
+	}
+
+	path := filepath.Join(dir, "broken.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("policies:\n  viewer: [read]\n"), 0o644))
+	_, err := baccess.LoadConfigFromFile(path)
+	assert.ErrorContains(t, err, "failed to parse config YAML: line 2, column 11")
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := baccess.LoadConfig(strings.NewReader(`{"policies":{"viewer":{"allow":["read"]}}}`), baccess.FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, cfg.Policies["viewer"].Allow)
+
+	cfg, err = baccess.LoadConfig(strings.NewReader("policies:\n  viewer:\n    allow: [read]\n"), baccess.FormatYAML)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, cfg.Policies["viewer"].Allow)
+
+	_, err = baccess.LoadConfig(strings.NewReader(`{}`), "toml")
+	assert.EqualError(t, err, "unsupported config format 'toml'")
+
+	_, err = baccess.LoadConfig(iotest.ErrReader(errors.New("connection reset")), baccess.FormatJSON)
+	assert.EqualError(t, err, "failed to read config: connection reset")
+}
+
+func TestLoadConfigFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"policies/billing.json": {Data: []byte(`{"policies":{"accountant":{"allow":["invoice.read"]}},"inherits":{"accountant":["viewer"]},"conditions":{"sameTeam":"subject.team == resource.team"}}`)},
+		"policies/docs.yaml":    {Data: []byte("algorithm: deny-overrides\npolicies:\n  viewer:\n    allow: [read]\n")},
+		"policies/README.md":    {Data: []byte("not a policy")},
+	}
+
+	cfg, err := baccess.LoadConfigFS(fsys, "policies/*.json")
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Policies, 1)
+
+	fsys["policies/docs.json"] = &fstest.MapFile{Data: []byte(`{"policies":{"viewer":{"allow":["read"]}},"algorithm":"deny-overrides"}`)}
+	cfg, err = baccess.LoadConfigFS(fsys, "policies/*.json")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"invoice.read"}, cfg.Policies["accountant"].Allow)
+	assert.Equal(t, []string{"read"}, cfg.Policies["viewer"].Allow)
+	assert.Equal(t, []string{"viewer"}, cfg.Inherits["accountant"])
+	assert.Equal(t, baccess.DenyOverrides, cfg.Algorithm)
+	assert.Equal(t, map[string]string{"sameTeam": "subject.team == resource.team"}, cfg.Conditions)
+
+	fsys["policies/billing.json"] = &fstest.MapFile{Data: []byte(`{"policies":{"accountant":{"allow":["invoice.read"]}},"inherits":{"accountant":["viewer"]},"conditions":{"sameTeam":"subject.team == resource.team"},"matcher":"hierarchy"}`)}
+	cfg, err = baccess.LoadConfigFS(fsys, "policies/*.json")
+	assert.NoError(t, err)
+	assert.Equal(t, baccess.HierarchyMatching, cfg.Matcher)
+
+	fsys["policies/team.json"] = &fstest.MapFile{Data: []byte(`{"policies":{"viewer":{"allow":["write"]}},"inherits":{"accountant":["admin"]},"algorithm":"permit-overrides","conditions":{"sameTeam":"true"},"matcher":"glob"}`)}
+	fsys["policies/zz.json"] = &fstest.MapFile{Data: []byte(`{"policies":`)}
+	_, err = baccess.LoadConfigFS(fsys, "policies/*.json")
+	assert.ErrorContains(t, err, "role 'viewer' is defined in both policies/docs.json and policies/team.json")
+	assert.ErrorContains(t, err, "inheritance of role 'accountant' is defined in both policies/billing.json and policies/team.json")
+	assert.ErrorContains(t, err, "condition 'sameTeam' is defined in both policies/billing.json and policies/team.json")
+	assert.ErrorContains(t, err, "combining algorithm 'permit-overrides' in policies/team.json conflicts with 'deny-overrides' in policies/docs.json")
+	assert.ErrorContains(t, err, "action matcher 'glob' in policies/team.json conflicts with 'hierarchy' in policies/billing.json")
+	assert.ErrorContains(t, err, "policies/zz.json: failed to parse config JSON")
+
+	_, err = baccess.LoadConfigFS(fsys, "missing/*.json")
+	assert.EqualError(t, err, "no config files match pattern 'missing/*.json'")
+
+	_, err = baccess.LoadConfigFS(fsys, "[")
+	assert.ErrorContains(t, err, "invalid config pattern '['")
+}
+
+func TestBuildEvaluator_BooleanConditions(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+			"isCollaborator": baccess.SubjectInResourceList[auth_test_utils.MockSubject, auth_test_utils.MockResource, string](
+				func(s auth_test_utils.MockSubject) string { return s.ID },
+				func(r auth_test_utils.MockResource) []string { return r.Collaborators },
+			),
+			"isArchived": baccess.ResourceMatches[auth_test_utils.MockSubject, auth_test_utils.MockResource, string](
+				func(r auth_test_utils.MockResource) string { return r.Status },
+				"archived",
+			),
+		},
+	}
+
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor": {Allow: []string{
+				"edit:(isOwner | isCollaborator) & !isArchived",
+				"comment:isOwner|isCollaborator",
+				"archive:isOwner&!isArchived",
+			}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.NoError(t, err)
+
+	editor := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	req := func(action string, resource auth_test_utils.MockResource) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: editor, Resource: resource, Action: action}
+	}
+	owned := auth_test_utils.MockResource{OwnerID: "u1", Status: "draft"}
+	shared := auth_test_utils.MockResource{OwnerID: "u2", Collaborators: []string{"u1"}, Status: "draft"}
+	archived := auth_test_utils.MockResource{OwnerID: "u1", Status: "archived"}
+	foreign := auth_test_utils.MockResource{OwnerID: "u2", Status: "draft"}
+
+	assert.True(t, evaluator.Evaluate(req("edit", owned)))
+	assert.True(t, evaluator.Evaluate(req("edit", shared)))
+	assert.False(t, evaluator.Evaluate(req("edit", archived)))
+	assert.False(t, evaluator.Evaluate(req("edit", foreign)))
+	assert.True(t, evaluator.Evaluate(req("comment", shared)))
+	assert.True(t, evaluator.Evaluate(req("comment", archived)))
+	assert.False(t, evaluator.Evaluate(req("comment", foreign)))
+	assert.True(t, evaluator.Evaluate(req("archive", owned)))
+	assert.False(t, evaluator.Evaluate(req("archive", archived)))
+	assert.True(t, evaluator.Evaluate(req("comment:isOwner|isCollaborator", shared)))
+
+	// Syntax errors point at the offending column.
+	for condition, message := range map[string]string{
+		"isOwner|":            "invalid condition 'isOwner|': column 9: expected predicate name, found end of condition",
+		"(isOwner":            "invalid condition '(isOwner': column 9: expected ')'",
+		"isOwner isArchived":  "invalid condition 'isOwner isArchived': column 9: unexpected 'i'",
+		"isOwner&|isArchived": "invalid condition 'isOwner&|isArchived': column 9: expected predicate name, found '|'",
+	} {
+		_, err := baccess.BuildEvaluator(&baccess.Config{
+			Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"edit:" + condition}}},
+		}, rbac, provider)
+		assert.EqualError(t, err, "role 'editor': rule 'edit:"+condition+"': "+message)
+
+		var syntaxErr *baccess.ConditionError
+		assert.True(t, errors.As(err, &syntaxErr))
+	}
+
+	// Every unknown predicate is reported and the rule fails closed.
+	broken, err := baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"edit:isOwner|isReviewer|isLead"}}},
+	}, rbac, provider)
+	assert.ErrorContains(t, err, "failed to get predicate 'isReviewer'")
+	assert.ErrorContains(t, err, "failed to get predicate 'isLead'")
+	assert.False(t, broken.Evaluate(req("edit", owned)))
+
+	// Predicate names that are not valid in expressions keep working as
+	// whole conditions, as they did before the expression syntax.
+	provider.Predicates["team:owner"] = isOwner()
+	provider.Predicates["org/owner"] = isOwner()
+	provider.Predicates["is owner"] = isOwner()
+	legacy, err := baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"read:team:owner", "share:org/owner", "edit:is owner"}}},
+	}, rbac, provider)
+	require.NoError(t, err)
+	for _, action := range []string{"read", "share", "edit", "read:team:owner"} {
+		assert.True(t, legacy.Evaluate(req(action, owned)), action)
+		assert.False(t, legacy.Evaluate(req(action, foreign)), action)
+	}
+	_, err = baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"read:team:lead"}}},
+	}, rbac, provider)
+	assert.EqualError(t, err, "role 'editor': rule 'read:team:lead': invalid condition 'team:lead': column 5: unexpected ':'")
+}
+
+func TestBuildEvaluator_PredicateFactories(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	registry := baccess.NewRegistry[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	registry.Register("isOwner", isOwner())
+	registry.RegisterFactory("attrEquals", func(args []string) (baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]], error) {
+		if err := baccess.ExpectArgs(args, 2); err != nil {
+			return nil, err
+		}
+		return baccess.SubjectAttrEquals[auth_test_utils.MockSubject, auth_test_utils.MockResource](args[0], args[1]), nil
+	})
+	registry.RegisterFactory("levelAtLeast", func(args []string) (baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]], error) {
+		if err := baccess.ExpectArgs(args, 1); err != nil {
+			return nil, err
+		}
+		level, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("level must be an integer: %w", err)
+		}
+		return func(req baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]) bool {
+			return req.Subject.Rank >= level
+		}, nil
+	})
+
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"staff": {Allow: []string{
+				"approve:attrEquals(department, finance)",
+				"read:levelAtLeast(3)|isOwner",
+				"comment:attrEquals('title', 'Head of R&D')",
+			}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, registry)
+	assert.NoError(t, err)
+
+	req := func(subject auth_test_utils.MockSubject, action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		subject.Roles = []string{"staff"}
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+			Subject:  subject,
+			Resource: auth_test_utils.MockResource{OwnerID: "owner"},
+			Action:   action,
+		}
+	}
+	finance := auth_test_utils.MockSubject{ID: "f", Rank: 1, Attributes: map[string]any{"department": "finance"}}
+	senior := auth_test_utils.MockSubject{ID: "s", Rank: 5, Attributes: map[string]any{"department": "sales", "title": "Head of R&D"}}
+	owner := auth_test_utils.MockSubject{ID: "owner", Rank: 1}
+
+	assert.True(t, evaluator.Evaluate(req(finance, "approve")))
+	assert.False(t, evaluator.Evaluate(req(senior, "approve")))
+	assert.False(t, evaluator.Evaluate(req(finance, "read")))
+	assert.True(t, evaluator.Evaluate(req(senior, "read")))
+	assert.True(t, evaluator.Evaluate(req(owner, "read")))
+	assert.True(t, evaluator.Evaluate(req(senior, "comment")))
+	assert.False(t, evaluator.Evaluate(req(finance, "comment")))
+
+	// Arity and type errors from factories are reported per rule.
+	_, err = baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"staff": {Allow: []string{"approve:attrEquals(department)", "read:levelAtLeast(high)", "edit:isEditor()"}},
+		},
+	}, rbac, registry)
+	assert.ErrorContains(t, err, "role 'staff': rule 'approve:attrEquals(department)': failed to get predicate 'attrEquals(department)': expected 2 argument(s), got 1")
+	assert.ErrorContains(t, err, "role 'staff': rule 'read:levelAtLeast(high)': failed to get predicate 'levelAtLeast(high)': level must be an integer")
+	assert.ErrorContains(t, err, "predicate factory not found: isEditor")
+
+	_, err = baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"staff": {Allow: []string{"read:levelAtLeast(3"}}},
+	}, rbac, registry)
+	assert.ErrorContains(t, err, "invalid condition 'levelAtLeast(3': column 15: expected ',' or ')', found end of condition")
+
+	// Providers without factory support reject factory calls.
+	_, err = baccess.BuildEvaluator(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"staff": {Allow: []string{"read:levelAtLeast(3)"}}},
+	}, rbac, &MockPredicateProvider{})
+	assert.ErrorContains(t, err, "does not support predicate factories")
+
+	err = baccess.ValidateConfig(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"staff": {Allow: []string{"read:levelAtLeast(x)", "edit:attrEquals(a,b)"}}},
+	}, registry)
+	assert.EqualError(t, err, `role 'staff': allow[0] 'read:levelAtLeast(x)': invalid condition 'levelAtLeast(x)': level must be an integer: strconv.Atoi: parsing "x": invalid syntax`)
+}
+
+func TestBuildEvaluator_Conditions(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+		},
+	}
+
+	cfg, err := baccess.LoadConfigFromYAML([]byte(`
+conditions:
+  sameDepartment: subject.department == resource.department && resource.status != 'archived'
+  withoutMFA: env.mfa != true
+policies:
+  editor:
+    allow: ["edit:sameDepartment|isOwner", "approve"]
+    deny: ["approve:withoutMFA"]
+`))
+	require.NoError(t, err)
+
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	require.NoError(t, err)
+
+	req := func(subjectDept, resourceDept, status string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+			Subject:  auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}, Attributes: map[string]any{"department": subjectDept}},
+			Resource: auth_test_utils.MockResource{OwnerID: "u2", Attributes: map[string]any{"department": resourceDept, "status": status}},
+			Action:   "edit",
+		}
+	}
+	assert.True(t, evaluator.Evaluate(req("finance", "finance", "draft")))
+	assert.False(t, evaluator.Evaluate(req("finance", "finance", "archived")))
+	assert.False(t, evaluator.Evaluate(req("finance", "sales", "draft")))
+
+	// Environment attributes are addressable as env.<name>.
+	approve := req("finance", "finance", "draft")
+	approve.Action = "approve"
+	assert.False(t, evaluator.Evaluate(approve))
+	approve.Environment = baccess.Environment{"mfa": true}
+	assert.True(t, evaluator.Evaluate(approve))
+
+	// Compile errors are reported with their position, and rules using the
+	// broken condition fail closed.
+	cfg.Conditions["sameDepartment"] = "subject.department == resource.department &&"
+	broken, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.ErrorContains(t, err, "condition 'sameDepartment': invalid expression 'subject.department == resource.department &&': column 45: expected a value, found end of expression")
+	assert.ErrorContains(t, err, "role 'editor': rule 'edit:sameDepartment|isOwner': failed to get predicate 'sameDepartment': condition 'sameDepartment' does not compile")
+	assert.False(t, broken.Evaluate(req("finance", "finance", "draft")))
+
+	err = baccess.ValidateConfig(&baccess.Config{
+		Conditions: map[string]string{"bad name": "true", "typo": "subject.mfa && 'yes'"},
+		Policies:   map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"edit:typo", "read:missing"}}},
+	}, provider)
+	assert.EqualError(t, err, strings.Join([]string{
+		"conditions 'bad name': condition name may only contain letters, digits, '_', '-' and '.'",
+		"conditions 'typo': invalid expression 'subject.mfa && 'yes'': column 13: operator && expects bool operands, got string",
+		"role 'editor': allow[0] 'edit:typo': unknown condition 'typo': condition 'typo' does not compile",
+		"role 'editor': allow[1] 'read:missing': unknown condition 'missing': predicate not found",
+	}, "\n"))
+}
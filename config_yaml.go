@@ -0,0 +1,104 @@
+package baccess
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfigFromYAML parses a YAML policy document. Errors point at the line
+// and column of the offending entry.
+func LoadConfigFromYAML(data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config YAML: %w", err)
+	}
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil {
+		if n := findYAMLMismatch(&root, reflect.TypeOf(cfg)); n != nil {
+			return nil, fmt.Errorf("failed to parse config YAML: line %d, column %d: %w", n.Line, n.Column, err)
+		}
+		return nil, fmt.Errorf("failed to parse config YAML: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// findYAMLMismatch walks n alongside the Go type t and returns the first node
+// that cannot be decoded into its corresponding field, since yaml.v3 type
+// errors only carry a line number.
+func findYAMLMismatch(n *yaml.Node, t reflect.Type) *yaml.Node {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return findYAMLMismatch(n.Content[0], t)
+	case yaml.AliasNode:
+		return findYAMLMismatch(n.Alias, t)
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return findYAMLMismatch(n, t.Elem())
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return n
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if field, ok := yamlField(t, n.Content[i].Value); ok {
+				if bad := findYAMLMismatch(n.Content[i+1], field.Type); bad != nil {
+					return bad
+				}
+			}
+		}
+		return nil
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return n
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if bad := findYAMLMismatch(n.Content[i+1], t.Elem()); bad != nil {
+				return bad
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return n
+		}
+		for _, item := range n.Content {
+			if bad := findYAMLMismatch(item, t.Elem()); bad != nil {
+				return bad
+			}
+		}
+		return nil
+	}
+
+	if err := n.Decode(reflect.New(t).Interface()); err != nil {
+		return n
+	}
+
+	return nil
+}
+
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,243 @@
+package baccess
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ContextPredicate is a predicate that may block, honor cancellation and
+// fail, e.g. one that looks up group membership in a database.
+//
+// Errors fail closed: a predicate that returns an error is never satisfied,
+// and And, Or and Not return (false, err) as soon as an evaluated operand
+// fails. In particular Not does not turn a failure into success. Use OnError
+// to choose a different result for a specific predicate.
+type ContextPredicate[T any] func(ctx context.Context, entity T) (bool, error)
+
+func (p ContextPredicate[T]) IsSatisfiedBy(ctx context.Context, entity T) (bool, error) {
+	return p(ctx, entity)
+}
+
+// And is satisfied when both predicates are. other is not evaluated when p
+// is false or fails.
+func (p ContextPredicate[T]) And(other ContextPredicate[T]) ContextPredicate[T] {
+	return func(ctx context.Context, entity T) (bool, error) {
+		ok, err := p(ctx, entity)
+		if err != nil || !ok {
+			return false, err
+		}
+
+		return failOnError(other(ctx, entity))
+	}
+}
+
+// Or is satisfied when either predicate is. other is not evaluated when p is
+// true or fails.
+func (p ContextPredicate[T]) Or(other ContextPredicate[T]) ContextPredicate[T] {
+	return func(ctx context.Context, entity T) (bool, error) {
+		ok, err := p(ctx, entity)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+
+		return failOnError(other(ctx, entity))
+	}
+}
+
+// Not negates p. A failure of p is still a failure.
+func (p ContextPredicate[T]) Not() ContextPredicate[T] {
+	return func(ctx context.Context, entity T) (bool, error) {
+		ok, err := p(ctx, entity)
+		if err != nil {
+			return false, err
+		}
+
+		return !ok, nil
+	}
+}
+
+// failOnError makes sure a failed evaluation is never reported as satisfied.
+func failOnError(ok bool, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// OnError returns a predicate that reports result instead of failing when p
+// returns an error, e.g. p.OnError(true) for a best-effort check that should
+// not block access when its backend is unavailable.
+func (p ContextPredicate[T]) OnError(result bool) ContextPredicate[T] {
+	return func(ctx context.Context, entity T) (bool, error) {
+		ok, err := p(ctx, entity)
+		if err != nil {
+			return result, nil
+		}
+
+		return ok, nil
+	}
+}
+
+// AsPredicate adapts p to a Predicate evaluated with context.Background().
+// An error counts as not satisfied.
+func (p ContextPredicate[T]) AsPredicate() Predicate[T] {
+	return func(entity T) bool {
+		ok, err := p(context.Background(), entity)
+		return err == nil && ok
+	}
+}
+
+// AsContextPredicate adapts p to a ContextPredicate that never fails.
+func AsContextPredicate[T any](p Predicate[T]) ContextPredicate[T] {
+	return func(_ context.Context, entity T) (bool, error) {
+		return p(entity), nil
+	}
+}
+
+// AddContextPolicy registers a policy that allows the action when p is
+// satisfied. Evaluate and Explain call p with context.Background() and treat
+// an error as not satisfied.
+func (e *Evaluator[S, R]) AddContextPolicy(action string, p ContextPredicate[AccessRequest[S, R]]) {
+	e.add(policy[S, R]{key: action, effect: EffectAllow, pred: failClosed(p, EffectAllow), ctxPred: p})
+}
+
+// AddContextDenyPolicy registers a policy that denies the action when p is
+// satisfied. Evaluate and Explain call p with context.Background() and treat
+// an error as satisfied, so a failing deny policy still denies.
+func (e *Evaluator[S, R]) AddContextDenyPolicy(action string, p ContextPredicate[AccessRequest[S, R]]) {
+	e.add(policy[S, R]{key: action, effect: EffectDeny, pred: failClosed(p, EffectDeny), ctxPred: p})
+}
+
+// failClosed adapts p for Evaluate: an error never grants access.
+func failClosed[S any, R any](p ContextPredicate[AccessRequest[S, R]], effect Effect) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		ok, err := p(context.Background(), req)
+		if err != nil {
+			return effect == EffectDeny
+		}
+
+		return ok
+	}
+}
+
+// EvaluateContext evaluates req like Evaluate, passing ctx to context
+// policies and stopping when ctx is done. If ctx is done or a policy fails
+// before a decision is reached, access is denied and the error is returned.
+func (e *Evaluator[S, R]) EvaluateContext(ctx context.Context, req AccessRequest[S, R]) (bool, error) {
+	set := e.load()
+	candidates := set.index.candidates(req.Action)
+	if e.auditSink == nil {
+		allowed, _, err := e.evaluateContext(ctx, set, candidates, req)
+		return allowed, err
+	}
+
+	start := time.Now()
+	allowed, decisive, err := e.evaluateContext(ctx, set, candidates, req)
+	e.audit(set, candidates, req, allowed, decisive, start, err)
+
+	return allowed, err
+}
+
+// DecideContext evaluates req like EvaluateContext, and is recorded like it,
+// but also describes the decision. Unlike Explain it stops at the decisive
+// policy and passes ctx to context policies, so the Decision always agrees
+// with the outcome; Policies holds only the decisive policy, if any. If the
+// evaluation fails, access is denied and the error is returned and given as
+// the reason.
+func (e *Evaluator[S, R]) DecideContext(ctx context.Context, req AccessRequest[S, R]) (Decision, error) {
+	decision := Decision{Action: req.Action, Effect: EffectDeny, Algorithm: e.algorithm}
+	if !e.algorithm.valid() {
+		decision.Reason = fmt.Sprintf("unknown combining algorithm '%s'", e.algorithm)
+	}
+
+	start := time.Now()
+	set := e.load()
+	candidates := set.index.candidates(req.Action)
+	allowed, decisive, err := e.evaluateContext(ctx, set, candidates, req)
+	if e.auditSink != nil {
+		e.audit(set, candidates, req, allowed, decisive, start, err)
+	}
+
+	switch {
+	case decision.Reason != "":
+	case err != nil:
+		decision.Reason = fmt.Sprintf("evaluation failed: %v", err)
+	case decisive == -1:
+		decision.fallThrough(len(candidates) > 0)
+	default:
+		p := &set.policies[decisive]
+		trace := PolicyTrace{Key: p.key, Effect: p.effect, Role: p.role, Condition: p.condition, Satisfied: true}
+		if e.matcher == nil {
+			trace.MatchRule = matchAction(p.key, req.Action)
+		}
+		if p.rolePred != nil {
+			trace.RoleMet, trace.ConditionMet = true, true
+		}
+		decision.Policies = []PolicyTrace{trace}
+		decision.decideBy(&decision.Policies[0])
+	}
+
+	return decision, err
+}
+
+// evaluateContext is the context-aware form of evaluate.
+func (e *Evaluator[S, R]) evaluateContext(
+	ctx context.Context,
+	set *policySet[S, R],
+	candidates []int32,
+	req AccessRequest[S, R],
+) (bool, int32, error) {
+	allowedBy := int32(-1)
+	for _, i := range candidates {
+		if err := ctx.Err(); err != nil {
+			return false, -1, err
+		}
+		p := &set.policies[i]
+
+		switch e.algorithm {
+		case PermitOverrides:
+			if p.effect != EffectAllow {
+				continue
+			}
+		case FirstApplicable:
+		default:
+			if p.effect == EffectAllow && allowedBy != -1 {
+				continue
+			}
+		}
+
+		satisfied, err := p.satisfiedContext(ctx, req)
+		if err != nil {
+			return false, -1, fmt.Errorf("policy %q: %w", p.key, err)
+		}
+		if !satisfied {
+			continue
+		}
+
+		switch {
+		case e.algorithm == FirstApplicable:
+			return p.effect == EffectAllow, i, nil
+		case p.effect == EffectDeny:
+			return false, i, nil
+		case e.algorithm == PermitOverrides:
+			return true, i, nil
+		default:
+			allowedBy = i
+		}
+	}
+
+	return allowedBy != -1, allowedBy, nil
+}
+
+func (p *policy[S, R]) satisfiedContext(ctx context.Context, req AccessRequest[S, R]) (bool, error) {
+	if p.ctxPred != nil {
+		return p.ctxPred(ctx, req)
+	}
+
+	return p.pred(req), nil
+}
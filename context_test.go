@@ -0,0 +1,212 @@
+package baccess_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+)
+
+var errBackend = errors.New("group service unavailable")
+
+func ctxResult(ok bool, err error) baccess.ContextPredicate[int] {
+	return func(ctx context.Context, _ int) (bool, error) {
+		return ok, err
+	}
+}
+
+func TestContextPredicate(t *testing.T) {
+	ctx := context.Background()
+	yes, no, fails := ctxResult(true, nil), ctxResult(false, nil), ctxResult(true, errBackend)
+
+	tests := []struct {
+		name    string
+		pred    baccess.ContextPredicate[int]
+		want    bool
+		wantErr error
+	}{
+		{"and", yes.And(yes), true, nil},
+		{"and false", yes.And(no), false, nil},
+		{"and short-circuits on false", no.And(fails), false, nil},
+		{"and fails", yes.And(fails), false, errBackend},
+		{"or", no.Or(yes), true, nil},
+		{"or short-circuits on true", yes.Or(fails), true, nil},
+		{"or fails", no.Or(fails), false, errBackend},
+		{"or fails before trying other", fails.Or(yes), false, errBackend},
+		{"not", no.Not(), true, nil},
+		{"not fails closed", fails.Not(), false, errBackend},
+		{"on error true", fails.OnError(true), true, nil},
+		{"on error false", fails.Not().OnError(false), false, nil},
+		{"on error keeps result", no.OnError(true), false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.pred.IsSatisfiedBy(ctx, 0)
+			assert.Equal(t, tt.want, got)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestContextPredicateAdapters(t *testing.T) {
+	isPositive := baccess.Predicate[int](func(n int) bool { return n > 0 })
+
+	lifted := baccess.AsContextPredicate(isPositive)
+	ok, err := lifted(context.Background(), 1)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	assert.True(t, lifted.AsPredicate().IsSatisfiedBy(1))
+	assert.False(t, lifted.AsPredicate().IsSatisfiedBy(-1))
+	assert.False(t, ctxResult(true, errBackend).AsPredicate().IsSatisfiedBy(1))
+}
+
+func TestEvaluator_EvaluateContext(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	inGroup := func(ctx context.Context, req request) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if req.Subject.ID == "" {
+			return false, errBackend
+		}
+		return req.Subject.ID == "member", nil
+	}
+	suspended := func(_ context.Context, req request) (bool, error) {
+		if req.Subject.ID == "broken" {
+			return false, errBackend
+		}
+		return req.Subject.Department == "suspended", nil
+	}
+
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	evaluator.AddContextPolicy("read", inGroup)
+	evaluator.AddPolicy("list", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	evaluator.AddContextDenyPolicy("*", suspended)
+
+	req := func(id, department, action string) request {
+		return request{Subject: auth_test_utils.MockSubject{ID: id, Department: department}, Action: action}
+	}
+	ctx := context.Background()
+
+	allowed, err := evaluator.EvaluateContext(ctx, req("member", "", "read"))
+	assert.True(t, allowed)
+	assert.NoError(t, err)
+
+	allowed, err = evaluator.EvaluateContext(ctx, req("guest", "", "read"))
+	assert.False(t, allowed)
+	assert.NoError(t, err)
+
+	allowed, err = evaluator.EvaluateContext(ctx, req("member", "suspended", "read"))
+	assert.False(t, allowed)
+	assert.NoError(t, err)
+
+	// A failing allow policy denies with the error.
+	allowed, err = evaluator.EvaluateContext(ctx, req("", "", "read"))
+	assert.False(t, allowed)
+	assert.ErrorIs(t, err, errBackend)
+	assert.ErrorContains(t, err, `policy "read"`)
+
+	// A failing deny policy denies even when an allow policy is satisfied.
+	allowed, err = evaluator.EvaluateContext(ctx, req("broken", "", "list"))
+	assert.False(t, allowed)
+	assert.ErrorIs(t, err, errBackend)
+
+	// Evaluate uses the same fail-closed semantics without reporting the error.
+	assert.True(t, evaluator.Evaluate(req("member", "", "read")))
+	assert.False(t, evaluator.Evaluate(req("", "", "read")))
+	assert.False(t, evaluator.Evaluate(req("broken", "", "list")))
+	assert.True(t, evaluator.Evaluate(req("x", "", "list")))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	allowed, err = evaluator.EvaluateContext(canceled, req("member", "", "read"))
+	assert.False(t, allowed)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Under permit-overrides deny policies are never consulted.
+	permissive := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](baccess.WithCombiningAlgorithm(baccess.PermitOverrides))
+	permissive.AddContextDenyPolicy("*", suspended)
+	permissive.AddPolicy("list", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	allowed, err = permissive.EvaluateContext(ctx, req("broken", "", "list"))
+	assert.True(t, allowed)
+	assert.NoError(t, err)
+
+	holder := baccess.NewEvaluatorHolder[auth_test_utils.MockSubject, auth_test_utils.MockResource](nil)
+	allowed, err = holder.EvaluateContext(ctx, req("member", "", "read"))
+	assert.False(t, allowed)
+	assert.EqualError(t, err, "no evaluator loaded")
+	holder.Swap(evaluator)
+	allowed, err = holder.EvaluateContext(ctx, req("member", "", "read"))
+	assert.True(t, allowed)
+	assert.NoError(t, err)
+}
+
+func TestEvaluator_DecideContext(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+	type suspendedKey struct{}
+
+	// Suspension is only known from the caller's context, which Explain does
+	// not see.
+	suspended := func(ctx context.Context, _ request) (bool, error) {
+		if err, ok := ctx.Value(suspendedKey{}).(error); ok {
+			return false, err
+		}
+		return ctx.Value(suspendedKey{}) == true, nil
+	}
+
+	sink := &recordingSink{}
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](baccess.WithAuditSink(sink))
+	evaluator.AddPolicy("read", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	evaluator.AddContextDenyPolicy("*", suspended)
+
+	req := func(action string) request { return request{Action: action} }
+	ctx := context.Background()
+
+	decision, err := evaluator.DecideContext(ctx, req("read"))
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, `allowed by allow policy "read"`, decision.Reason)
+	assert.Equal(t, []baccess.PolicyTrace{{Key: "read", Effect: baccess.EffectAllow, MatchRule: 2, Satisfied: true, Decisive: true}}, decision.Policies)
+
+	decision, err = evaluator.DecideContext(context.WithValue(ctx, suspendedKey{}, true), req("read"))
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, baccess.EffectDeny, decision.Effect)
+	assert.Equal(t, `denied by deny policy "*"`, decision.Reason)
+	assert.Equal(t, `allowed by allow policy "read"`, evaluator.Explain(req("read")).Reason)
+
+	decision, err = evaluator.DecideContext(ctx, req("write"))
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, `no allow policy for action "write" is satisfied`, decision.Reason)
+	assert.Empty(t, decision.Policies)
+
+	decision, err = evaluator.DecideContext(context.WithValue(ctx, suspendedKey{}, errBackend), req("read"))
+	assert.ErrorIs(t, err, errBackend)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, `evaluation failed: policy "*": group service unavailable`, decision.Reason)
+
+	// Decisions are recorded like EvaluateContext's.
+	assert.Len(t, sink.events, 4)
+	assert.Equal(t, "*", sink.events[1].Policy)
+	assert.Equal(t, `policy "*": group service unavailable`, sink.events[3].Error)
+
+	holder := baccess.NewEvaluatorHolder[auth_test_utils.MockSubject, auth_test_utils.MockResource](nil)
+	decision, err = holder.DecideContext(ctx, req("read"))
+	assert.EqualError(t, err, "no evaluator loaded")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no evaluator loaded", decision.Reason)
+	holder.Swap(evaluator)
+	decision, err = holder.DecideContext(ctx, req("read"))
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
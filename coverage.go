@@ -0,0 +1,225 @@
+package baccess
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+// Coverage counts how often each rule in Config.Policies was exercised, so
+// that rules and conditions no test reaches can be found. Pass it to
+// BuildEvaluator with WithCoverage; it has no effect on NewEvaluator.
+//
+// A rule is hit when its condition is evaluated for a subject that holds the
+// rule's role. Evaluate stops at the first decisive policy, so rules behind
+// it are not hit; Explain evaluates every matching policy and gives complete
+// counts.
+type Coverage struct {
+	mu    sync.Mutex
+	rules []*ruleCounter
+	index map[ruleKey]*ruleCounter
+}
+
+type ruleKey struct {
+	role   string
+	rule   string
+	effect Effect
+}
+
+type ruleCounter struct {
+	ruleKey
+	condition string
+	trueHits  atomic.Uint64
+	falseHits atomic.Uint64
+}
+
+// RuleCoverage reports the counts of a single rule.
+type RuleCoverage struct {
+	Role string
+	// Rule is the rule as written in the config, e.g. "edit:isOwner".
+	Rule      string
+	Effect    Effect
+	Condition string
+	// True and False count the outcomes of the condition.
+	True  uint64
+	False uint64
+}
+
+func (r RuleCoverage) Hits() uint64 {
+	return r.True + r.False
+}
+
+func (r RuleCoverage) Covered() bool {
+	return r.Hits() > 0
+}
+
+// Status summarizes r: "uncovered", "never true", "never false" or "ok".
+// Unconditional rules are never reported as "never false".
+func (r RuleCoverage) Status() string {
+	switch {
+	case !r.Covered():
+		return "uncovered"
+	case r.True == 0:
+		return "never true"
+	case r.False == 0 && r.Condition != "*":
+		return "never false"
+	default:
+		return "ok"
+	}
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{index: make(map[ruleKey]*ruleCounter)}
+}
+
+// WithCoverage records the evaluation of every rule built by BuildEvaluator
+// in c. Evaluators rebuilt with the same Coverage, e.g. by a Watcher, keep
+// counting into the rules they share.
+func WithCoverage(c *Coverage) EvaluatorOption {
+	return func(o *evaluatorOptions) {
+		o.coverage = c
+	}
+}
+
+// rule returns the counter for a rule, registering it on first use.
+func (c *Coverage) rule(role, rule string, effect Effect, condition string) *ruleCounter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ruleKey{role: role, rule: rule, effect: effect}
+	if counter, ok := c.index[key]; ok {
+		return counter
+	}
+
+	counter := &ruleCounter{ruleKey: key, condition: condition}
+	c.index[key] = counter
+	c.rules = append(c.rules, counter)
+
+	return counter
+}
+
+// instrumentCondition wraps condition so that its outcome is counted
+// whenever the subject holds the rule's role.
+func instrumentCondition[S any, R any](
+	counter *ruleCounter,
+	rolePred Predicate[AccessRequest[S, R]],
+	condition Predicate[AccessRequest[S, R]],
+) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		ok := condition(req)
+		if rolePred(req) {
+			if ok {
+				counter.trueHits.Add(1)
+			} else {
+				counter.falseHits.Add(1)
+			}
+		}
+		return ok
+	}
+}
+
+// Rules returns the counts of every rule, in the order BuildEvaluator added
+// them.
+func (c *Coverage) Rules() []RuleCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rules := make([]RuleCoverage, len(c.rules))
+	for i, counter := range c.rules {
+		rules[i] = RuleCoverage{
+			Role:      counter.role,
+			Rule:      counter.rule,
+			Effect:    counter.effect,
+			Condition: counter.condition,
+			True:      counter.trueHits.Load(),
+			False:     counter.falseHits.Load(),
+		}
+	}
+
+	return rules
+}
+
+// Reset sets every count back to zero.
+func (c *Coverage) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, counter := range c.rules {
+		counter.trueHits.Store(0)
+		counter.falseHits.Store(0)
+	}
+}
+
+type coverageReport struct {
+	Rules   []RuleCoverage
+	Covered int
+	Percent float64
+}
+
+func (c *Coverage) report() coverageReport {
+	report := coverageReport{Rules: c.Rules()}
+	for _, rule := range report.Rules {
+		if rule.Covered() {
+			report.Covered++
+		}
+	}
+	if len(report.Rules) > 0 {
+		report.Percent = 100 * float64(report.Covered) / float64(len(report.Rules))
+	}
+
+	return report
+}
+
+// WriteText writes the coverage summary and a table of every rule.
+func (c *Coverage) WriteText(w io.Writer) error {
+	report := c.report()
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "policy coverage: %d of %d rules covered (%.1f%%)\n\n", report.Covered, len(report.Rules), report.Percent)
+	fmt.Fprintln(table, "ROLE\tEFFECT\tRULE\tHITS\tTRUE\tFALSE\tSTATUS")
+	for _, rule := range report.Rules {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n", rule.Role, rule.Effect, rule.Rule, rule.Hits(), rule.True, rule.False, rule.Status())
+	}
+
+	return table.Flush()
+}
+
+var coverageHTML = template.Must(template.New("coverage").Funcs(template.FuncMap{
+	"class": func(status string) string { return strings.ReplaceAll(status, " ", "-") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Policy coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #ddd; text-align: left; }
+td.count { text-align: right; font-variant-numeric: tabular-nums; }
+tr.uncovered { background: #fdd; }
+tr.never-true, tr.never-false { background: #ffd; }
+tr.ok { background: #dfd; }
+</style>
+</head>
+<body>
+<h1>Policy coverage</h1>
+<p>{{.Covered}} of {{len .Rules}} rules covered ({{printf "%.1f" .Percent}}%)</p>
+<table>
+<tr><th>Role</th><th>Effect</th><th>Rule</th><th>Hits</th><th>True</th><th>False</th><th>Status</th></tr>
+{{- range .Rules}}
+<tr class="{{.Status | class}}"><td>{{.Role}}</td><td>{{.Effect}}</td><td><code>{{.Rule}}</code></td><td class="count">{{.Hits}}</td><td class="count">{{.True}}</td><td class="count">{{.False}}</td><td>{{.Status}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the coverage report as a standalone HTML page, with the
+// uncovered rules and one-sided conditions highlighted.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	return coverageHTML.Execute(w, c.report())
+}
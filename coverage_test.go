@@ -0,0 +1,85 @@
+package baccess_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	coverage := baccess.NewCoverage()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{
+			"isOwner": isOwner(),
+			"isDraft": isDraft(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"admin":  {Allow: []string{"*"}},
+			"editor": {Allow: []string{"edit:isOwner", "read"}, Deny: []string{"edit:isDraft"}},
+			"viewer": {Allow: []string{"read"}},
+		},
+	}
+	build := func() *baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider, baccess.WithCoverage(coverage))
+		require.NoError(t, err)
+		return evaluator
+	}
+	evaluator := build()
+
+	editor := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	owned := auth_test_utils.MockResource{ID: "doc1", OwnerID: "u1", Status: "published"}
+	other := auth_test_utils.MockResource{ID: "doc2", OwnerID: "u2", Status: "published"}
+
+	evaluator.Explain(request{Subject: editor, Resource: owned, Action: "edit"})
+	evaluator.Explain(request{Subject: editor, Resource: other, Action: "edit"})
+	// Rebuilding with the same Coverage keeps counting into the same rules.
+	evaluator = build()
+	assert.True(t, evaluator.Evaluate(request{Subject: editor, Resource: other, Action: "read"}))
+
+	assert.Equal(t, []baccess.RuleCoverage{
+		{Role: "admin", Rule: "*", Effect: baccess.EffectAllow, Condition: "*"},
+		{Role: "editor", Rule: "edit:isDraft", Effect: baccess.EffectDeny, Condition: "isDraft", False: 2},
+		{Role: "editor", Rule: "edit:isOwner", Effect: baccess.EffectAllow, Condition: "isOwner", True: 1, False: 1},
+		{Role: "editor", Rule: "read", Effect: baccess.EffectAllow, Condition: "*", True: 1},
+		{Role: "viewer", Rule: "read", Effect: baccess.EffectAllow, Condition: "*"},
+	}, coverage.Rules())
+
+	var text bytes.Buffer
+	require.NoError(t, coverage.WriteText(&text))
+	assert.Equal(t, `policy coverage: 3 of 5 rules covered (60.0%)
+
+ROLE    EFFECT  RULE          HITS  TRUE  FALSE  STATUS
+admin   allow   *             0     0     0      uncovered
+editor  deny    edit:isDraft  2     0     2      never true
+editor  allow   edit:isOwner  2     1     1      ok
+editor  allow   read          1     1     0      ok
+viewer  allow   read          0     0     0      uncovered
+`, text.String())
+
+	var html bytes.Buffer
+	require.NoError(t, coverage.WriteHTML(&html))
+	assert.Contains(t, html.String(), "3 of 5 rules covered (60.0%)")
+	assert.Contains(t, html.String(), `<tr class="never-true"><td>editor</td><td>deny</td><td><code>edit:isDraft</code></td>`)
+	assert.Contains(t, html.String(), `<tr class="uncovered"><td>admin</td>`)
+
+	coverage.Reset()
+	for _, rule := range coverage.Rules() {
+		assert.False(t, rule.Covered(), rule.Rule)
+	}
+}
+
+func TestRuleCoverage_Status(t *testing.T) {
+	assert.Equal(t, "uncovered", baccess.RuleCoverage{Condition: "isOwner"}.Status())
+	assert.Equal(t, "never true", baccess.RuleCoverage{Condition: "isOwner", False: 1}.Status())
+	assert.Equal(t, "never false", baccess.RuleCoverage{Condition: "isOwner", True: 1}.Status())
+	assert.Equal(t, "ok", baccess.RuleCoverage{Condition: "isOwner", True: 1, False: 1}.Status())
+	assert.Equal(t, "ok", baccess.RuleCoverage{Condition: "*", True: 1}.Status())
+}
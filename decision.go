@@ -0,0 +1,146 @@
+package baccess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Decision is a structured explanation of an authorization decision.
+type Decision struct {
+	Action    string
+	Allowed   bool
+	Effect    Effect
+	Algorithm CombiningAlgorithm
+	// Reason is a one-line, human readable summary of the decision.
+	Reason string
+	// Policies lists every policy whose key matched the action, in evaluation order.
+	Policies []PolicyTrace
+}
+
+// PolicyTrace records how a single matching policy was evaluated.
+type PolicyTrace struct {
+	Key    string
+	Effect Effect
+	// MatchRule is the action matching rule (1-5) under which Key matched,
+	// or 0 under matchers other than DefaultMatcher.
+	MatchRule int
+	// Role and Condition are set for policies built from a Config.
+	Role         string
+	RoleMet      bool
+	Condition    string
+	ConditionMet bool
+	// Satisfied is the result of the policy's full predicate.
+	Satisfied bool
+	// Decisive marks the policy that determined the final effect.
+	Decisive bool
+}
+
+func (t PolicyTrace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %q (rule %d)", t.Effect, t.Key, t.MatchRule)
+	if t.Role != "" {
+		fmt.Fprintf(&b, " role %q=%t condition %q=%t", t.Role, t.RoleMet, t.Condition, t.ConditionMet)
+	}
+	fmt.Fprintf(&b, " -> %t", t.Satisfied)
+
+	return b.String()
+}
+
+// Explain evaluates req like Evaluate, but evaluates every matching policy and
+// returns a Decision describing how the outcome was reached.
+func (e *Evaluator[S, R]) Explain(req AccessRequest[S, R]) Decision {
+	decision := Decision{
+		Action:    req.Action,
+		Effect:    EffectDeny,
+		Algorithm: e.algorithm,
+	}
+
+	if !e.algorithm.valid() {
+		decision.Reason = fmt.Sprintf("unknown combining algorithm '%s'", e.algorithm)
+		return decision
+	}
+
+	set := e.load()
+	for _, i := range set.index.candidates(req.Action) {
+		p := &set.policies[i]
+		trace := PolicyTrace{
+			Key:       p.key,
+			Effect:    p.effect,
+			Role:      p.role,
+			Condition: p.condition,
+		}
+		if e.matcher == nil {
+			trace.MatchRule = matchAction(p.key, req.Action)
+		}
+		if p.rolePred != nil {
+			trace.RoleMet = p.rolePred.IsSatisfiedBy(req)
+			trace.ConditionMet = p.conditionPred.IsSatisfiedBy(req)
+			trace.Satisfied = trace.RoleMet && trace.ConditionMet
+		} else {
+			trace.Satisfied = p.pred.IsSatisfiedBy(req)
+		}
+		decision.Policies = append(decision.Policies, trace)
+	}
+
+	decisive := decide(e.algorithm, decision.Policies)
+	if decisive == -1 {
+		decision.fallThrough(len(decision.Policies) > 0)
+		return decision
+	}
+	decision.decideBy(&decision.Policies[decisive])
+
+	return decision
+}
+
+// fallThrough records the implicit deny, depending on whether any policy
+// matched the action.
+func (d *Decision) fallThrough(matched bool) {
+	if matched {
+		d.Reason = fmt.Sprintf("no allow policy for action %q is satisfied", d.Action)
+	} else {
+		d.Reason = fmt.Sprintf("no policy matches action %q", d.Action)
+	}
+}
+
+// decideBy records winner as the decisive policy.
+func (d *Decision) decideBy(winner *PolicyTrace) {
+	winner.Decisive = true
+	d.Effect = winner.Effect
+	d.Allowed = winner.Effect == EffectAllow
+
+	verb := "allowed"
+	if !d.Allowed {
+		verb = "denied"
+	}
+	d.Reason = fmt.Sprintf("%s by %s policy %q", verb, winner.Effect, winner.Key)
+	if winner.Role != "" {
+		d.Reason += fmt.Sprintf(" for role %q", winner.Role)
+	}
+}
+
+// decide applies the combining algorithm to evaluated traces and returns the
+// index of the decisive policy, or -1 when access falls through to the
+// implicit deny.
+func decide(algorithm CombiningAlgorithm, traces []PolicyTrace) int {
+	firstAllow, firstDeny := -1, -1
+	for i, t := range traces {
+		if !t.Satisfied {
+			continue
+		}
+		if algorithm == FirstApplicable {
+			return i
+		}
+		if t.Effect == EffectAllow && firstAllow == -1 {
+			firstAllow = i
+		}
+		if t.Effect == EffectDeny && firstDeny == -1 {
+			firstDeny = i
+		}
+	}
+
+	if algorithm == PermitOverrides || firstDeny == -1 {
+		return firstAllow
+	}
+
+	return firstDeny
+}
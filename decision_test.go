@@ -0,0 +1,73 @@
+package baccess_test
+
+import (
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluator_Explain(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor":     {Allow: []string{"read", "delete:isOwner"}},
+			"contractor": {Deny: []string{"delete"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+	assert.NoError(t, err)
+
+	owner := auth_test_utils.MockSubject{ID: "user1", Roles: []string{"editor"}}
+	doc := auth_test_utils.MockResource{OwnerID: "user1"}
+
+	decision := evaluator.Explain(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: owner, Resource: doc, Action: "delete"})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, baccess.EffectAllow, decision.Effect)
+	assert.Equal(t, baccess.DenyOverrides, decision.Algorithm)
+	assert.Equal(t, `allowed by allow policy "delete:isOwner" for role "editor"`, decision.Reason)
+	assert.Equal(t, []baccess.PolicyTrace{
+		{Key: "delete", Effect: baccess.EffectDeny, MatchRule: 2, Role: "contractor", RoleMet: false, Condition: "*", ConditionMet: true},
+		{Key: "delete:isOwner", Effect: baccess.EffectAllow, MatchRule: 5, Role: "editor", RoleMet: true, Condition: "isOwner", ConditionMet: true, Satisfied: true, Decisive: true},
+	}, decision.Policies)
+
+	contractor := auth_test_utils.MockSubject{ID: "user1", Roles: []string{"editor", "contractor"}}
+	decision = evaluator.Explain(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: contractor, Resource: doc, Action: "delete"})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, baccess.EffectDeny, decision.Effect)
+	assert.Equal(t, `denied by deny policy "delete" for role "contractor"`, decision.Reason)
+	assert.True(t, decision.Policies[0].Decisive)
+
+	decision = evaluator.Explain(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: owner, Resource: doc, Action: "publish"})
+	assert.False(t, decision.Allowed)
+	assert.Empty(t, decision.Policies)
+	assert.Equal(t, `no policy matches action "publish"`, decision.Reason)
+
+	other := auth_test_utils.MockSubject{ID: "user2", Roles: []string{"editor"}}
+	decision = evaluator.Explain(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: other, Resource: doc, Action: "delete:isOwner"})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, `no allow policy for action "delete:isOwner" is satisfied`, decision.Reason)
+	assert.Equal(t, 2, decision.Policies[1].MatchRule)
+	assert.False(t, decision.Policies[1].ConditionMet)
+}
+
+func TestEvaluator_ExplainUnnamedPolicies(t *testing.T) {
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	evaluator.AddPolicy("*", isAdmin())
+	evaluator.AddPolicy("read", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+
+	req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: auth_test_utils.MockSubject{ID: "user"}, Action: "read:summary"}
+	decision := evaluator.Explain(req)
+	assert.Equal(t, evaluator.Evaluate(req), decision.Allowed)
+	assert.Len(t, decision.Policies, 2)
+	assert.Equal(t, 1, decision.Policies[0].MatchRule)
+	assert.False(t, decision.Policies[0].Satisfied)
+	assert.Equal(t, 4, decision.Policies[1].MatchRule)
+	assert.Equal(t, `allow "read" (rule 4) -> true`, decision.Policies[1].String())
+}
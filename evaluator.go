@@ -1,77 +1,277 @@
 package baccess
 
 import (
-	"strings"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Effect is the outcome a policy produces when its predicate is satisfied.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// CombiningAlgorithm decides how the effects of several matching policies
+// are combined into a single decision.
+type CombiningAlgorithm string
+
+const (
+	// DenyOverrides denies if any matching deny policy is satisfied, otherwise
+	// allows if any matching allow policy is satisfied. This is the default.
+	DenyOverrides CombiningAlgorithm = "deny-overrides"
+	// PermitOverrides allows if any matching allow policy is satisfied,
+	// regardless of deny policies.
+	PermitOverrides CombiningAlgorithm = "permit-overrides"
+	// FirstApplicable uses the effect of the first satisfied matching policy,
+	// in the order policies were added.
+	FirstApplicable CombiningAlgorithm = "first-applicable"
+)
+
+func (a CombiningAlgorithm) valid() bool {
+	switch a {
+	case DenyOverrides, PermitOverrides, FirstApplicable:
+		return true
+	}
+
+	return false
+}
+
+type EvaluatorOption func(*evaluatorOptions)
+
+type evaluatorOptions struct {
+	algorithm CombiningAlgorithm
+	auditSink AuditSink
+	coverage  *Coverage
+	matcher   ActionMatcher
+}
+
+// WithCombiningAlgorithm selects how matching allow and deny policies are combined.
+// An evaluator with an unknown algorithm denies every request; BuildEvaluator
+// reports an unknown Config.Algorithm as an error instead.
+func WithCombiningAlgorithm(algorithm CombiningAlgorithm) EvaluatorOption {
+	return func(o *evaluatorOptions) {
+		o.algorithm = algorithm
+	}
+}
+
+type policy[S any, R any] struct {
+	key    string
+	effect Effect
+	pred   Predicate[AccessRequest[S, R]]
+	// ctxPred is set for policies added with AddContextPolicy or
+	// AddContextDenyPolicy; pred then wraps it for Evaluate.
+	ctxPred ContextPredicate[AccessRequest[S, R]]
+	// partial reduces the part of the policy that depends on the resource to
+	// a Residual, if it has a partial form; see PartialEvaluate.
+	partial func(AccessRequest[S, R]) Residual
+
+	// role and condition name the parts of pred for policies built from a
+	// Config; they are empty for policies added with AddPolicy/AddDenyPolicy.
+	role          string
+	rolePred      Predicate[AccessRequest[S, R]]
+	condition     string
+	conditionPred Predicate[AccessRequest[S, R]]
+}
+
+// Evaluator is safe for concurrent use. Policies are added under a mutex,
+// while evaluation reads an immutable, compiled snapshot of the policies
+// without taking any lock. Adding a policy discards the snapshot and the next
+// evaluation (or Compile) publishes a new one.
 type Evaluator[S any, R any] struct {
-	policies map[string]Predicate[AccessRequest[S, R]]
+	mu        sync.Mutex
+	policies  []policy[S, R]
+	algorithm CombiningAlgorithm
+	snapshot  atomic.Pointer[policySet[S, R]]
+	auditSink AuditSink
+	// matcher is nil for DefaultMatcher, which uses the bucketed policyIndex.
+	matcher ActionMatcher
 }
 
-func NewEvaluator[S any, R any]() *Evaluator[S, R] {
+// policySet is an immutable snapshot of an Evaluator's policies together with
+// their compiled index.
+type policySet[S any, R any] struct {
+	policies []policy[S, R]
+	index    actionIndex
+}
+
+func NewEvaluator[S any, R any](opts ...EvaluatorOption) *Evaluator[S, R] {
+	options := evaluatorOptions{algorithm: DenyOverrides}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	matcher := options.matcher
+	if _, ok := matcher.(DefaultMatcher); ok {
+		matcher = nil
+	}
+
 	return &Evaluator[S, R]{
-		policies: make(map[string]Predicate[AccessRequest[S, R]]),
+		algorithm: options.algorithm,
+		auditSink: options.auditSink,
+		matcher:   matcher,
 	}
 }
 
 func (e *Evaluator[S, R]) AddPolicy(action string, p Predicate[AccessRequest[S, R]]) {
-	if existing, ok := e.policies[action]; ok {
-		e.policies[action] = existing.Or(p)
-	} else {
-		e.policies[action] = p
+	e.add(policy[S, R]{key: action, effect: EffectAllow, pred: p})
+}
+
+// AddDenyPolicy registers a policy that denies the action when p is satisfied.
+func (e *Evaluator[S, R]) AddDenyPolicy(action string, p Predicate[AccessRequest[S, R]]) {
+	e.add(policy[S, R]{key: action, effect: EffectDeny, pred: p})
+}
+
+// addRolePolicy registers a policy that is satisfied when the subject holds
+// role and the named condition holds, keeping both parts for Explain.
+func (e *Evaluator[S, R]) addRolePolicy(
+	action string,
+	effect Effect,
+	role string,
+	rolePred Predicate[AccessRequest[S, R]],
+	condition string,
+	conditionPred Predicate[AccessRequest[S, R]],
+	conditionPartial func(AccessRequest[S, R]) Residual,
+) {
+	e.add(policy[S, R]{
+		key:           action,
+		effect:        effect,
+		pred:          rolePred.And(conditionPred),
+		role:          role,
+		rolePred:      rolePred,
+		condition:     condition,
+		conditionPred: conditionPred,
+		partial:       conditionPartial,
+	})
+}
+
+func (e *Evaluator[S, R]) add(p policy[S, R]) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.policies = append(e.policies, p)
+	e.snapshot.Store(nil)
+}
+
+// Compile indexes the policies by action so that Evaluate only visits the
+// policies matching the requested action. It runs automatically on the first
+// evaluation after a policy is added; calling it up front moves that cost out
+// of the request path.
+func (e *Evaluator[S, R]) Compile() {
+	e.compile()
+}
+
+func (e *Evaluator[S, R]) compile() *policySet[S, R] {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if set := e.snapshot.Load(); set != nil {
+		return set
+	}
+
+	// An unknown combining algorithm fails closed: no policy ever applies.
+	var policies []policy[S, R]
+	if e.algorithm.valid() {
+		policies = slices.Clone(e.policies)
+	}
+	set := &policySet[S, R]{
+		policies: policies,
+		index:    compileActionIndex(e.matcher, policies),
 	}
+	e.snapshot.Store(set)
+
+	return set
 }
 
-func (e *Evaluator[S, R]) Evaluate(req AccessRequest[S, R]) bool {
-	var combinedPredicate Predicate[AccessRequest[S, R]]
+// pattern returns the action pattern p is matched by.
+// load returns the current compiled snapshot, compiling one if needed.
+func (e *Evaluator[S, R]) load() *policySet[S, R] {
+	if set := e.snapshot.Load(); set != nil {
+		return set
+	}
 
-	reqActionBase := req.Action
-	reqActionCondition := ""
-	if colonIndex := strings.Index(req.Action, ":"); colonIndex != -1 {
-		reqActionBase = req.Action[:colonIndex]
-		reqActionCondition = req.Action[colonIndex+1:]
+	return e.compile()
+}
+
+// Algorithm returns the combining algorithm used by Evaluate.
+func (e *Evaluator[S, R]) Algorithm() CombiningAlgorithm {
+	return e.algorithm
+}
+
+func (e *Evaluator[S, R]) Evaluate(req AccessRequest[S, R]) bool {
+	set := e.load()
+	if e.auditSink == nil {
+		allowed, _ := e.evaluate(set, set.index.candidates(req.Action), req)
+		return allowed
 	}
 
-	for policyKey, p := range e.policies {
-		match := false
-		policyKeyBase := policyKey
-		policyKeyCondition := ""
-		if colonIndex := strings.Index(policyKey, ":"); colonIndex != -1 {
-			policyKeyBase = policyKey[:colonIndex]
-			policyKeyCondition = policyKey[colonIndex+1:]
-		}
+	start := time.Now()
+	candidates := set.index.candidates(req.Action)
+	allowed, decisive := e.evaluate(set, candidates, req)
+	e.audit(set, candidates, req, allowed, decisive, start, nil)
 
-		// Rule 1: Global wildcard policy (e.g., policy "*")
-		if policyKey == "*" {
-			match = true
-		} else if policyKey == req.Action { // Rule 2: Exact match (e.g., "read" == "read", "delete:isOwner" == "delete:isOwner")
-			match = true
-		} else if policyKeyCondition == "*" && policyKeyBase == reqActionBase {
-			// Rule 3: Policy with action-level wildcard matches request with same base action
-			// (e.g., "update:*" matches "update:title" or "update")
-			match = true
-		} else if policyKeyCondition == "" && policyKeyBase == reqActionBase && reqActionCondition != "" {
-			// Rule 4: Policy for a base action matches request for the same base action with a condition
-			// (e.g., policy "read" matches request "read:something")
-			match = true
-		} else if reqActionCondition == "" && policyKeyCondition != "" && reqActionBase == policyKeyBase {
-			// Rule 5: Request for a base action matches policy for the same base action with a condition
-			// (e.g., request "delete" matches policy "delete:isOwner")
-			match = true
-		}
+	return allowed
+}
+
+// evaluate applies the combining algorithm to the candidate policies. It also
+// returns the index of the policy that decided the outcome, or -1 when access
+// falls through to the implicit deny.
+func (e *Evaluator[S, R]) evaluate(set *policySet[S, R], candidates []int32, req AccessRequest[S, R]) (bool, int32) {
+	allowedBy := int32(-1)
+	for _, i := range candidates {
+		p := &set.policies[i]
 
-		if match {
-			if combinedPredicate == nil {
-				combinedPredicate = p
-			} else {
-				combinedPredicate = combinedPredicate.Or(p)
+		switch e.algorithm {
+		case PermitOverrides:
+			if p.effect == EffectAllow && p.pred.IsSatisfiedBy(req) {
+				return true, i
+			}
+		case FirstApplicable:
+			if p.pred.IsSatisfiedBy(req) {
+				return p.effect == EffectAllow, i
+			}
+		default:
+			if p.effect == EffectDeny {
+				if p.pred.IsSatisfiedBy(req) {
+					return false, i
+				}
+			} else if allowedBy == -1 && p.pred.IsSatisfiedBy(req) {
+				allowedBy = i
 			}
 		}
 	}
 
-	if combinedPredicate == nil {
-		return false
+	return allowedBy != -1, allowedBy
+}
+
+// matchAction reports which of the matching rules (1-5) matches policyKey
+// against the requested action, or 0 if none does. It is the reference
+// definition of the rules that policyIndex precomputes.
+func matchAction(policyKey, action string) int {
+	policyKeyBase, policyKeyCondition := splitAction(policyKey)
+	reqActionBase, reqActionCondition := splitAction(action)
+
+	// Rule 1: Global wildcard policy (e.g., policy "*")
+	if policyKey == "*" {
+		return 1
+	} else if policyKey == action { // Rule 2: Exact match (e.g., "read" == "read", "delete:isOwner" == "delete:isOwner")
+		return 2
+	} else if policyKeyCondition == "*" && policyKeyBase == reqActionBase {
+		// Rule 3: Policy with action-level wildcard matches request with same base action
+		// (e.g., "update:*" matches "update:title" or "update")
+		return 3
+	} else if policyKeyCondition == "" && policyKeyBase == reqActionBase && reqActionCondition != "" {
+		// Rule 4: Policy for a base action matches request for the same base action with a condition
+		// (e.g., policy "read" matches request "read:something")
+		return 4
+	} else if reqActionCondition == "" && policyKeyCondition != "" && reqActionBase == policyKeyBase {
+		// Rule 5: Request for a base action matches policy for the same base action with a condition
+		// (e.g., request "delete" matches policy "delete:isOwner")
+		return 5
 	}
 
-	return combinedPredicate.IsSatisfiedBy(req)
+	return 0
 }
@@ -201,3 +201,98 @@ func TestEvaluator_Evaluate(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluator_CombiningAlgorithms(t *testing.T) {
+	subject := auth_test_utils.MockSubject{ID: "user1"}
+	resource := auth_test_utils.MockResource{OwnerID: "user1"}
+	req := func(action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: subject, Resource: resource, Action: action}
+	}
+
+	build := func(opts ...baccess.EvaluatorOption) *baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](opts...)
+		evaluator.AddDenyPolicy("delete", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+		evaluator.AddPolicy("*", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+		evaluator.AddDenyPolicy("archive", alwaysFalse[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+		return evaluator
+	}
+
+	denyOverrides := build()
+	assert.Equal(t, baccess.DenyOverrides, denyOverrides.Algorithm())
+	assert.False(t, denyOverrides.Evaluate(req("delete")))
+	assert.False(t, denyOverrides.Evaluate(req("delete:isOwner")))
+	assert.True(t, denyOverrides.Evaluate(req("read")))
+	assert.True(t, denyOverrides.Evaluate(req("archive")))
+
+	permitOverrides := build(baccess.WithCombiningAlgorithm(baccess.PermitOverrides))
+	assert.True(t, permitOverrides.Evaluate(req("delete")))
+	assert.True(t, permitOverrides.Evaluate(req("read")))
+
+	firstApplicable := build(baccess.WithCombiningAlgorithm(baccess.FirstApplicable))
+	assert.False(t, firstApplicable.Evaluate(req("delete")))
+	assert.True(t, firstApplicable.Evaluate(req("read")))
+
+	allowFirst := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](baccess.WithCombiningAlgorithm(baccess.FirstApplicable))
+	allowFirst.AddPolicy("delete", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	allowFirst.AddDenyPolicy("delete", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	assert.True(t, allowFirst.Evaluate(req("delete")))
+
+	denyOnly := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	denyOnly.AddDenyPolicy("read", alwaysFalse[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	assert.False(t, denyOnly.Evaluate(req("read")))
+
+	// An unknown algorithm fails closed.
+	unknown := build(baccess.WithCombiningAlgorithm("deny-unless-permit"))
+	assert.Equal(t, baccess.CombiningAlgorithm("deny-unless-permit"), unknown.Algorithm())
+	assert.False(t, unknown.Evaluate(req("read")))
+	assert.Empty(t, unknown.Filter(subject, "read", []auth_test_utils.MockResource{resource}))
+	actions, everything := unknown.AllowedActions(subject, resource)
+	assert.Empty(t, actions)
+	assert.False(t, everything)
+	decision := unknown.Explain(req("read"))
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "unknown combining algorithm 'deny-unless-permit'", decision.Reason)
+}
+
+func TestEvaluator_CompiledIndexMatchesRules(t *testing.T) {
+	keys := []string{"*", "read", "read:", "read:*", "read:own", "delete:isOwner", "delete:isAdmin", "update:*", "*:*"}
+
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	for _, key := range keys {
+		evaluator.AddPolicy(key, alwaysFalse[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	}
+	evaluator.Compile()
+
+	testCases := []struct {
+		action  string
+		matched []string
+	}{
+		{action: "read", matched: []string{"*", "read", "read:*", "read:own"}},
+		{action: "read:", matched: []string{"*", "read:", "read:*", "read:own"}},
+		{action: "read:own", matched: []string{"*", "read", "read:", "read:*", "read:own"}},
+		{action: "read:other", matched: []string{"*", "read", "read:", "read:*"}},
+		{action: "read:*", matched: []string{"*", "read", "read:", "read:*"}},
+		{action: "delete", matched: []string{"*", "delete:isOwner", "delete:isAdmin"}},
+		{action: "delete:isAdmin", matched: []string{"*", "delete:isAdmin"}},
+		{action: "update:title", matched: []string{"*", "update:*"}},
+		{action: "*:anything", matched: []string{"*", "*:*"}},
+		{action: "unknown", matched: []string{"*"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.action, func(t *testing.T) {
+			decision := evaluator.Explain(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Action: tc.action})
+
+			var matched []string
+			for _, trace := range decision.Policies {
+				assert.NotZero(t, trace.MatchRule, "policy %q should not be a candidate", trace.Key)
+				matched = append(matched, trace.Key)
+			}
+			assert.Equal(t, tc.matched, matched)
+		})
+	}
+
+	// Adding a policy after compilation invalidates the index.
+	evaluator.AddPolicy("archive", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	assert.True(t, evaluator.Evaluate(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Action: "archive"}))
+}
@@ -0,0 +1,721 @@
+package baccess
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CompileExpression compiles an inline condition such as
+//
+//	subject.department == resource.department && resource.status != 'archived'
+//
+// into a predicate. Values are read through Identifiable.GetID ("subject.id")
+// and Attributable.GetAttribute (any other name; further dots index into
+// map[string]any values). "env.<name>" reads AccessRequest.Environment. The
+// language supports string, number, boolean and null literals, list literals
+// ['a', 'b'], the operators ==, !=, <, <=, >, >=, in, &&, ||, ! and unary -,
+// and parentheses.
+//
+// The expression is parsed and type-checked once; errors are reported as an
+// *ExpressionError. A value of the wrong type at evaluation time (e.g.
+// comparing a string attribute with a number) makes the predicate false.
+func CompileExpression[S any, R any](expr string) (Predicate[AccessRequest[S, R]], error) {
+	tokens, err := lexExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{input: expr, tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok.pos, "unexpected %s", tok)
+	}
+
+	c := &exprCompiler[S, R]{input: expr}
+	eval, typ, err := c.compile(root)
+	if err != nil {
+		return nil, err
+	}
+	if typ != typeBool && typ != typeDynamic {
+		return nil, &ExpressionError{Expression: expr, Column: 1, Message: fmt.Sprintf("expression must be boolean, got %s", typ)}
+	}
+
+	return func(req AccessRequest[S, R]) bool {
+		v, ok := eval(&req)
+		b, isBool := v.(bool)
+		return ok && isBool && b
+	}, nil
+}
+
+// ExpressionError reports a syntax or type error in an inline expression.
+type ExpressionError struct {
+	Expression string
+	// Column is the 1-based position of the error within Expression.
+	Column  int
+	Message string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("invalid expression '%s': column %d: %s", e.Expression, e.Column, e.Message)
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOperator
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return "string " + strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "-", "(", ")", "[", "]", ",", "."}
+
+func lexExpression(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	pos := 0
+
+	for pos < len(input) {
+		c := input[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(input[pos+1:], c)
+			if end == -1 {
+				return nil, &ExpressionError{Expression: input, Column: pos + 1, Message: "unterminated string"}
+			}
+			tokens = append(tokens, exprToken{kind: tokString, text: input[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+		case c >= '0' && c <= '9':
+			start := pos
+			for pos < len(input) && (input[pos] >= '0' && input[pos] <= '9' || input[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: input[start:pos], pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := pos
+			for pos < len(input) && (input[pos] == '_' || input[pos] >= 'a' && input[pos] <= 'z' ||
+				input[pos] >= 'A' && input[pos] <= 'Z' || input[pos] >= '0' && input[pos] <= '9') {
+				pos++
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: input[start:pos], pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(input[pos:], op) {
+					tokens = append(tokens, exprToken{kind: tokOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &ExpressionError{Expression: input, Column: pos + 1, Message: fmt.Sprintf("unexpected character '%c'", c)}
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokEOF, pos: len(input)}), nil
+}
+
+// Parser
+
+type exprNode interface {
+	position() int
+}
+
+type (
+	literalNode struct {
+		pos   int
+		value any
+	}
+	// pathNode is a reference such as subject.department.
+	pathNode struct {
+		pos  int
+		root string
+		path []string
+	}
+	listNode struct {
+		pos   int
+		items []exprNode
+	}
+	unaryNode struct {
+		pos     int
+		op      string
+		operand exprNode
+	}
+	binaryNode struct {
+		pos         int
+		op          string
+		left, right exprNode
+	}
+)
+
+func (n literalNode) position() int { return n.pos }
+func (n pathNode) position() int    { return n.pos }
+func (n listNode) position() int    { return n.pos }
+func (n unaryNode) position() int   { return n.pos }
+func (n binaryNode) position() int  { return n.pos }
+
+type exprParser struct {
+	input  string
+	tokens []exprToken
+	next   int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.next]
+}
+
+func (p *exprParser) advance() exprToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords.
+func (p *exprParser) accept(texts ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokOperator && tok.kind != tokIdent {
+		return tok, false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			return p.advance(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		tok := p.peek()
+		return p.errorAt(tok.pos, "expected '%s', found %s", text, tok)
+	}
+	return nil
+}
+
+func (p *exprParser) errorAt(pos int, format string, args ...any) error {
+	return &ExpressionError{Expression: p.input, Column: pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+// Precedence, lowest first: ||, &&, comparisons (non-associative), unary.
+func (p *exprParser) parseExpr() (exprNode, error) {
+	return p.parseBinary(0)
+}
+
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.accept(exprPrecedence[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{pos: tok.pos, op: tok.text, left: left, right: right}
+
+		if level == len(exprPrecedence)-1 {
+			if next, ok := p.accept(exprPrecedence[level]...); ok {
+				return nil, p.errorAt(next.pos, "comparison operators cannot be chained; use parentheses")
+			}
+			return left, nil
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok, ok := p.accept("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{pos: tok.pos, op: tok.text, operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokString:
+		return literalNode{pos: tok.pos, value: tok.text}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok.pos, "invalid number '%s'", tok.text)
+		}
+		return literalNode{pos: tok.pos, value: n}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{pos: tok.pos, value: true}, nil
+		case "false":
+			return literalNode{pos: tok.pos, value: false}, nil
+		case "null":
+			return literalNode{pos: tok.pos, value: nil}, nil
+		}
+		return p.parsePath(tok)
+	case tokOperator:
+		switch tok.text {
+		case "(":
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			list := listNode{pos: tok.pos}
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			for {
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if _, ok := p.accept(","); !ok {
+					return list, p.expect("]")
+				}
+			}
+		}
+	}
+
+	return nil, p.errorAt(tok.pos, "expected a value, found %s", tok)
+}
+
+func (p *exprParser) parsePath(root exprToken) (exprNode, error) {
+	if !exprRoots[root.text] {
+		return nil, p.errorAt(root.pos, "unknown identifier '%s'; values must start with subject., resource. or env.", root.text)
+	}
+
+	node := pathNode{pos: root.pos, root: root.text}
+	for {
+		if _, ok := p.accept("."); !ok {
+			break
+		}
+		field := p.advance()
+		if field.kind != tokIdent {
+			return nil, p.errorAt(field.pos, "expected attribute name, found %s", field)
+		}
+		node.path = append(node.path, field.text)
+	}
+	if len(node.path) == 0 {
+		return nil, p.errorAt(root.pos, "'%s' must be followed by an attribute name", root.text)
+	}
+
+	return node, nil
+}
+
+var exprRoots = map[string]bool{"subject": true, "resource": true, "env": true}
+
+// Type checking and compilation
+
+type exprType int
+
+const (
+	typeDynamic exprType = iota // only known at evaluation time
+	typeBool
+	typeNumber
+	typeString
+	typeList
+	typeNull
+)
+
+func (t exprType) String() string {
+	return [...]string{"dynamic", "bool", "number", "string", "list", "null"}[t]
+}
+
+// exprEval evaluates a compiled node. ok is false when a value had the wrong
+// type at evaluation time.
+type exprEval[S any, R any] func(req *AccessRequest[S, R]) (v any, ok bool)
+
+type exprCompiler[S any, R any] struct {
+	input string
+}
+
+func (c *exprCompiler[S, R]) errorAt(node exprNode, format string, args ...any) error {
+	return &ExpressionError{Expression: c.input, Column: node.position() + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *exprCompiler[S, R]) compile(node exprNode) (exprEval[S, R], exprType, error) {
+	switch n := node.(type) {
+	case literalNode:
+		v := n.value
+		return func(*AccessRequest[S, R]) (any, bool) { return v, true }, typeOfLiteral(v), nil
+
+	case listNode:
+		items := make([]exprEval[S, R], len(n.items))
+		for i, item := range n.items {
+			eval, _, err := c.compile(item)
+			if err != nil {
+				return nil, 0, err
+			}
+			items[i] = eval
+		}
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			list := make([]any, len(items))
+			for i, item := range items {
+				v, ok := item(req)
+				if !ok {
+					return nil, false
+				}
+				list[i] = v
+			}
+			return list, true
+		}, typeList, nil
+
+	case pathNode:
+		eval, err := c.compilePath(n)
+		return eval, typeDynamic, err
+
+	case unaryNode:
+		operand, typ, err := c.compile(n.operand)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n.op == "!" {
+			if typ != typeBool && typ != typeDynamic {
+				return nil, 0, c.errorAt(n, "operator ! expects a bool operand, got %s", typ)
+			}
+			return func(req *AccessRequest[S, R]) (any, bool) {
+				v, ok := operand(req)
+				b, isBool := v.(bool)
+				return !b, ok && isBool
+			}, typeBool, nil
+		}
+		if typ != typeNumber && typ != typeDynamic {
+			return nil, 0, c.errorAt(n, "operator - expects a number operand, got %s", typ)
+		}
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			v, ok := operand(req)
+			f, isNumber := v.(float64)
+			return -f, ok && isNumber
+		}, typeNumber, nil
+
+	case binaryNode:
+		return c.compileBinary(n)
+	}
+
+	panic(fmt.Sprintf("baccess: unexpected expression node %T", node))
+}
+
+func (c *exprCompiler[S, R]) compileBinary(n binaryNode) (exprEval[S, R], exprType, error) {
+	left, lt, err := c.compile(n.left)
+	if err != nil {
+		return nil, 0, err
+	}
+	right, rt, err := c.compile(n.right)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		for _, t := range []exprType{lt, rt} {
+			if t != typeBool && t != typeDynamic {
+				return nil, 0, c.errorAt(n, "operator %s expects bool operands, got %s", n.op, t)
+			}
+		}
+		and := n.op == "&&"
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			v, ok := left(req)
+			b, isBool := v.(bool)
+			if !ok || !isBool {
+				return nil, false
+			}
+			// Short-circuit: false && x and true || x do not evaluate x.
+			if b != and {
+				return b, true
+			}
+			v, ok = right(req)
+			b, isBool = v.(bool)
+			return b, ok && isBool
+		}, typeBool, nil
+
+	case "==", "!=":
+		if lt != rt && lt != typeDynamic && rt != typeDynamic && lt != typeNull && rt != typeNull {
+			return nil, 0, c.errorAt(n, "cannot compare %s and %s", lt, rt)
+		}
+		negate := n.op == "!="
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			l, ok := left(req)
+			if !ok {
+				return nil, false
+			}
+			r, ok := right(req)
+			if !ok {
+				return nil, false
+			}
+			return equalValues(l, r) != negate, true
+		}, typeBool, nil
+
+	case "<", "<=", ">", ">=":
+		for _, t := range []exprType{lt, rt} {
+			if t != typeNumber && t != typeString && t != typeDynamic {
+				return nil, 0, c.errorAt(n, "operator %s expects numbers or strings, got %s", n.op, t)
+			}
+		}
+		if lt != rt && lt != typeDynamic && rt != typeDynamic {
+			return nil, 0, c.errorAt(n, "cannot compare %s and %s", lt, rt)
+		}
+		op := n.op
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			l, ok := left(req)
+			if !ok {
+				return nil, false
+			}
+			r, ok := right(req)
+			if !ok {
+				return nil, false
+			}
+			cmp, ok := compareValues(l, r)
+			if !ok {
+				return nil, false
+			}
+			switch op {
+			case "<":
+				return cmp < 0, true
+			case "<=":
+				return cmp <= 0, true
+			case ">":
+				return cmp > 0, true
+			default:
+				return cmp >= 0, true
+			}
+		}, typeBool, nil
+
+	case "in":
+		if rt != typeList && rt != typeDynamic {
+			return nil, 0, c.errorAt(n, "operator in expects a list on the right, got %s", rt)
+		}
+		return func(req *AccessRequest[S, R]) (any, bool) {
+			l, ok := left(req)
+			if !ok {
+				return nil, false
+			}
+			r, ok := right(req)
+			list, isList := r.([]any)
+			if !ok || !isList {
+				return nil, false
+			}
+			for _, item := range list {
+				if equalValues(l, item) {
+					return true, true
+				}
+			}
+			return false, true
+		}, typeBool, nil
+	}
+
+	panic(fmt.Sprintf("baccess: unexpected operator %s", n.op))
+}
+
+var (
+	identifiableType = reflect.TypeFor[Identifiable]()
+	attributableType = reflect.TypeFor[Attributable]()
+)
+
+func (c *exprCompiler[S, R]) compilePath(n pathNode) (exprEval[S, R], error) {
+	var value func(req *AccessRequest[S, R]) any
+	var typ reflect.Type
+	switch n.root {
+	case "subject":
+		typ = reflect.TypeFor[S]()
+		value = func(req *AccessRequest[S, R]) any { return req.Subject }
+	case "resource":
+		typ = reflect.TypeFor[R]()
+		value = func(req *AccessRequest[S, R]) any { return req.Resource }
+	case "env":
+		typ = attributableType
+		value = func(req *AccessRequest[S, R]) any { return req.Environment }
+	}
+
+	rest := n.path[1:]
+	var lookup func(v any) any
+	switch {
+	case n.path[0] == "id" && typ.Implements(identifiableType):
+		lookup = func(v any) any {
+			if id, ok := v.(Identifiable); ok {
+				return id.GetID()
+			}
+			return nil
+		}
+	case typ.Implements(attributableType):
+		key := n.path[0]
+		lookup = func(v any) any {
+			if attrs, ok := v.(Attributable); ok {
+				return attrs.GetAttribute(key)
+			}
+			return nil
+		}
+	case n.path[0] == "id":
+		return nil, c.errorAt(n, "%s.id requires %s to implement Identifiable", n.root, typ)
+	default:
+		return nil, c.errorAt(n, "%s.%s requires %s to implement Attributable", n.root, n.path[0], typ)
+	}
+
+	return func(req *AccessRequest[S, R]) (any, bool) {
+		v := lookup(value(req))
+		for _, key := range rest {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, true
+			}
+			v = m[key]
+		}
+		return normalizeValue(v), true
+	}, nil
+}
+
+func typeOfLiteral(v any) exprType {
+	switch v.(type) {
+	case bool:
+		return typeBool
+	case float64:
+		return typeNumber
+	case string:
+		return typeString
+	case nil:
+		return typeNull
+	}
+	return typeDynamic
+}
+
+// normalizeValue converts attribute values to the expression's value types:
+// every numeric kind becomes float64, named string and bool types become
+// string and bool, and slices become []any.
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string, float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+
+	return v
+}
+
+func equalValues(a, b any) bool {
+	if la, ok := a.([]any); ok {
+		lb, ok := b.([]any)
+		if !ok || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !equalValues(la[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta != nil && !ta.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+// compareValues orders two numbers or two strings.
+func compareValues(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	}
+	return 0, false
+}
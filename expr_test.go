@@ -0,0 +1,125 @@
+package baccess_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exprRequest = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+func TestCompileExpression(t *testing.T) {
+	type status string
+
+	req := exprRequest{
+		Subject: auth_test_utils.MockSubject{ID: "u1", Attributes: map[string]any{
+			"department": "finance",
+			"level":      3,
+			"mfa":        true,
+			"teams":      []string{"alpha", "beta"},
+			"address":    map[string]any{"country": "NL"},
+		}},
+		Resource: auth_test_utils.MockResource{ID: "doc1", Attributes: map[string]any{
+			"department": "finance",
+			"status":     status("draft"),
+			"owner":      "u1",
+			"minLevel":   int64(2),
+			"team":       "beta",
+		}},
+		Environment: baccess.Environment{"mfa": true, "ip": "10.0.0.1", "hour": 9},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"subject.department == resource.department && resource.status != 'archived'", true},
+		{"subject.department == 'sales' || resource.status == \"draft\"", true},
+		{"subject.id == resource.owner", true},
+		{"resource.id == 'doc1'", true},
+		{"subject.level >= resource.minLevel", true},
+		{"subject.level > 3", false},
+		{"subject.level <= 3 && subject.level < 4", true},
+		{"-subject.level < 0", true},
+		{"subject.mfa", true},
+		{"!subject.mfa", false},
+		{"!(subject.mfa && subject.level > 5)", true},
+		{"resource.team in subject.teams", true},
+		{"resource.status in ['published', 'archived']", false},
+		{"subject.address.country == 'NL'", true},
+		{"subject.address.city == null", true},
+		{"subject.missing == null", true},
+		{"subject.department < 'sales'", true},
+		{"env.mfa && env.hour >= 9 && env.hour < 17", true},
+		{"env.ip in ['10.0.0.1', '10.0.0.2']", true},
+		{"env.country == null", true},
+		// Values of the wrong type make the expression false rather than panic.
+		{"subject.department > 3", false},
+		{"subject.department", false},
+		{"!subject.department", false},
+		{"resource.team in subject.department", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pred, err := baccess.CompileExpression[auth_test_utils.MockSubject, auth_test_utils.MockResource](tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pred.IsSatisfiedBy(req))
+		})
+	}
+	// Without an environment every env attribute is null.
+	pred, err := baccess.CompileExpression[auth_test_utils.MockSubject, auth_test_utils.MockResource]("env.mfa")
+	require.NoError(t, err)
+	assert.False(t, pred.IsSatisfiedBy(exprRequest{}))
+}
+
+func TestCompileExpression_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+	}{
+		{"subject.department = 'x'", "column 20: unexpected character '='"},
+		{"subject.department == 'x", "column 23: unterminated string"},
+		{"subject.department ==", "column 22: expected a value, found end of expression"},
+		{"(subject.mfa", "column 13: expected ')', found end of expression"},
+		{"user.department == 'x'", "column 1: unknown identifier 'user'; values must start with subject., resource. or env."},
+		{"subject == 'x'", "column 1: 'subject' must be followed by an attribute name"},
+		{"subject.level > 1 > 0", "column 19: comparison operators cannot be chained; use parentheses"},
+		{"subject.mfa subject.level", "column 13: unexpected 'subject'"},
+		{"'a' == 1", "column 5: cannot compare string and number"},
+		{"'a' < true", "column 5: operator < expects numbers or strings, got bool"},
+		{"subject.mfa && 'yes'", "column 13: operator && expects bool operands, got string"},
+		{"!'yes'", "column 1: operator ! expects a bool operand, got string"},
+		{"subject.team in 'abc'", "column 14: operator in expects a list on the right, got string"},
+		{"subject.level", ""},
+		{"1 + 1", "column 3: unexpected character '+'"},
+		{"'yes'", "column 1: expression must be boolean, got string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := baccess.CompileExpression[auth_test_utils.MockSubject, auth_test_utils.MockResource](tt.expr)
+			if tt.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var exprErr *baccess.ExpressionError
+			require.True(t, errors.As(err, &exprErr))
+			assert.Equal(t, tt.expr, exprErr.Expression)
+			assert.EqualError(t, err, "invalid expression '"+tt.expr+"': "+tt.message)
+		})
+	}
+
+	// Attribute access is checked against the subject and resource types.
+	_, err := baccess.CompileExpression[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]("subject.department == 'x'")
+	assert.EqualError(t, err, "invalid expression 'subject.department == 'x'': column 1: subject.department requires auth_test_utils.MockRoleBearer to implement Attributable")
+	_, err = baccess.CompileExpression[auth_test_utils.MockSubject, auth_test_utils.MockAttributable]("resource.id == 'x'")
+	assert.NoError(t, err)
+	_, err = baccess.CompileExpression[auth_test_utils.MockSubject, auth_test_utils.MockRoleBearer]("resource.id == 'x'")
+	assert.EqualError(t, err, "invalid expression 'resource.id == 'x'': column 1: resource.id requires auth_test_utils.MockRoleBearer to implement Identifiable")
+}
@@ -0,0 +1,242 @@
+package baccess
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	parallelism int
+	environment Attributable
+}
+
+// WithParallelism evaluates up to n resources concurrently, for policies
+// whose predicates are expensive. The order of the results is unchanged.
+func WithParallelism(n int) FilterOption {
+	return func(o *filterOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithEnvironment sets the Environment of the requests evaluated for each
+// resource, as Evaluate would see it.
+func WithEnvironment(env Attributable) FilterOption {
+	return func(o *filterOptions) {
+		o.environment = env
+	}
+}
+
+// filterPlan is the set of policies that can still apply to a fixed subject
+// and action, resolved once for a whole collection of resources.
+type filterPlan[S any, R any] struct {
+	algorithm   CombiningAlgorithm
+	subject     S
+	action      string
+	environment Attributable
+	policies    []filterPolicy[S, R]
+	// matched holds the keys of every policy matching action, and sink
+	// records the decision for each resource when set.
+	matched []string
+	sink    AuditSink
+}
+
+type filterPolicy[S any, R any] struct {
+	key       string
+	effect    Effect
+	condition string
+	// pred and partial are the part of the policy that still depends on the
+	// resource.
+	pred    Predicate[AccessRequest[S, R]]
+	partial func(AccessRequest[S, R]) Residual
+}
+
+// plan resolves the policies matching action and checks the role of every
+// Config-built policy once, dropping those whose role the subject lacks.
+// Role predicates only inspect the subject, so the result holds for every
+// resource.
+func (e *Evaluator[S, R]) plan(subject S, action string, env Attributable) *filterPlan[S, R] {
+	set := e.load()
+	plan := &filterPlan[S, R]{algorithm: e.algorithm, subject: subject, action: action, environment: env}
+	req := AccessRequest[S, R]{Subject: subject, Action: action, Environment: env}
+
+	candidates := set.index.candidates(action)
+	if e.auditSink != nil {
+		plan.matched = make([]string, len(candidates))
+		for i, c := range candidates {
+			plan.matched[i] = set.policies[c].key
+		}
+	}
+	for _, i := range candidates {
+		p := &set.policies[i]
+		if p.rolePred == nil {
+			plan.policies = append(plan.policies, filterPolicy[S, R]{key: p.key, effect: p.effect, pred: p.pred, partial: p.partial})
+			continue
+		}
+		if p.rolePred.IsSatisfiedBy(req) {
+			plan.policies = append(plan.policies, filterPolicy[S, R]{
+				key:       p.key,
+				effect:    p.effect,
+				condition: p.condition,
+				pred:      p.conditionPred,
+				partial:   p.partial,
+			})
+		}
+	}
+
+	return plan
+}
+
+// allows evaluates the plan for resource like Evaluate would, and records the
+// decision like Evaluate when the plan has a sink.
+func (plan *filterPlan[S, R]) allows(resource R) bool {
+	req := AccessRequest[S, R]{Subject: plan.subject, Resource: resource, Action: plan.action, Environment: plan.environment}
+	if plan.sink == nil {
+		allowed, _ := plan.evaluate(req)
+		return allowed
+	}
+
+	start := time.Now()
+	allowed, decisive := plan.evaluate(req)
+	event := newAuditEvent(req, allowed, start)
+	event.Matched = plan.matched
+	if decisive != -1 {
+		event.Effect = plan.policies[decisive].effect
+		event.Policy = plan.policies[decisive].key
+	}
+	plan.sink.Record(event)
+
+	return allowed
+}
+
+// evaluate returns the outcome for req and the index of the decisive policy
+// in plan.policies, or -1 when access falls through to the implicit deny.
+func (plan *filterPlan[S, R]) evaluate(req AccessRequest[S, R]) (bool, int) {
+	allowedBy := -1
+	for i := range plan.policies {
+		p := &plan.policies[i]
+
+		switch plan.algorithm {
+		case PermitOverrides:
+			if p.effect == EffectAllow && p.pred.IsSatisfiedBy(req) {
+				return true, i
+			}
+		case FirstApplicable:
+			if p.pred.IsSatisfiedBy(req) {
+				return p.effect == EffectAllow, i
+			}
+		default:
+			if p.effect == EffectDeny {
+				if p.pred.IsSatisfiedBy(req) {
+					return false, i
+				}
+			} else if allowedBy == -1 && p.pred.IsSatisfiedBy(req) {
+				allowedBy = i
+			}
+		}
+	}
+
+	return allowedBy != -1, allowedBy
+}
+
+// allowsAll evaluates every resource, using up to workers goroutines.
+func (plan *filterPlan[S, R]) allowsAll(resources []R, workers int) []bool {
+	allowed := make([]bool, len(resources))
+	if workers <= 1 || len(resources) < 2 {
+		for i, resource := range resources {
+			allowed[i] = plan.allows(resource)
+		}
+		return allowed
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, len(resources)) {
+		wg.Go(func() {
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(resources) {
+					return
+				}
+				allowed[i] = plan.allows(resources[i])
+			}
+		})
+	}
+	wg.Wait()
+
+	return allowed
+}
+
+// Filter returns the resources on which subject may perform action, in their
+// original order. It is equivalent to calling Evaluate for every resource with
+// the Environment given by WithEnvironment (nil by default), but matches the
+// action and checks the subject's roles only once. With an audit sink, every
+// resource's decision is recorded as Evaluate would record it.
+func (e *Evaluator[S, R]) Filter(subject S, action string, resources []R, opts ...FilterOption) []R {
+	var options filterOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	plan := e.plan(subject, action, options.environment)
+	plan.sink = e.auditSink
+	if len(plan.policies) == 0 && plan.sink == nil {
+		return nil
+	}
+
+	var filtered []R
+	for i, ok := range plan.allowsAll(resources, options.parallelism) {
+		if ok {
+			filtered = append(filtered, resources[i])
+		}
+	}
+
+	return filtered
+}
+
+// FilterSeq is the iterator form of Filter. With WithParallelism it evaluates
+// resources in batches, so it reads somewhat ahead of the consumer.
+func (e *Evaluator[S, R]) FilterSeq(subject S, action string, resources iter.Seq[R], opts ...FilterOption) iter.Seq[R] {
+	var options filterOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(yield func(R) bool) {
+		plan := e.plan(subject, action, options.environment)
+		plan.sink = e.auditSink
+		if len(plan.policies) == 0 && plan.sink == nil {
+			return
+		}
+
+		if options.parallelism <= 1 {
+			for resource := range resources {
+				if plan.allows(resource) && !yield(resource) {
+					return
+				}
+			}
+			return
+		}
+
+		batch := make([]R, 0, options.parallelism*8)
+		flush := func() bool {
+			for i, ok := range plan.allowsAll(batch, options.parallelism) {
+				if ok && !yield(batch[i]) {
+					return false
+				}
+			}
+			batch = batch[:0]
+			return true
+		}
+		for resource := range resources {
+			batch = append(batch, resource)
+			if len(batch) == cap(batch) && !flush() {
+				return
+			}
+		}
+		flush()
+	}
+}
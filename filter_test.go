@@ -0,0 +1,152 @@
+package baccess_test
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluator_Filter(t *testing.T) {
+	subject := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor", "contractor"}}
+	resources := make([]auth_test_utils.MockResource, 40)
+	for i := range resources {
+		resources[i] = auth_test_utils.MockResource{
+			ID:      fmt.Sprint(i),
+			OwnerID: []string{"u1", "u2"}[i%2],
+			Status:  []string{"draft", "published", "archived"}[i%3],
+		}
+	}
+
+	for _, algorithm := range []baccess.CombiningAlgorithm{baccess.DenyOverrides, baccess.PermitOverrides, baccess.FirstApplicable} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+			provider := &MockPredicateProvider{
+				Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+					"isOwner": isOwner(),
+					"isDraft": isDraft(),
+				},
+			}
+			cfg := &baccess.Config{
+				Algorithm: algorithm,
+				Policies: map[string]baccess.RolePolicyConfig{
+					"admin":      {Allow: []string{"*"}},
+					"editor":     {Allow: []string{"read", "edit:isOwner|isDraft"}},
+					"contractor": {Deny: []string{"edit:isDraft"}},
+				},
+			}
+			evaluator, err := baccess.BuildEvaluator(cfg, rbac, provider)
+			require.NoError(t, err)
+			evaluator.AddDenyPolicy("read", func(req baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]) bool {
+				return req.Resource.Status == "archived"
+			})
+
+			for _, action := range []string{"read", "edit", "edit:isDraft", "delete"} {
+				var want []auth_test_utils.MockResource
+				for _, resource := range resources {
+					req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Subject: subject, Resource: resource, Action: action}
+					if evaluator.Evaluate(req) {
+						want = append(want, resource)
+					}
+				}
+
+				assert.Equal(t, want, evaluator.Filter(subject, action, resources), action)
+				assert.Equal(t, want, evaluator.Filter(subject, action, resources, baccess.WithParallelism(4)), action)
+				assert.Equal(t, want, slices.Collect(evaluator.FilterSeq(subject, action, slices.Values(resources))), action)
+				assert.Equal(t, want, slices.Collect(evaluator.FilterSeq(subject, action, slices.Values(resources), baccess.WithParallelism(3))), action)
+			}
+		})
+	}
+
+	// Role checks run once per call, not once per resource.
+	registry := baccess.NewRegistry[*countingSubject, auth_test_utils.MockResource]()
+	registry.Register("isDraft", func(req baccess.AccessRequest[*countingSubject, auth_test_utils.MockResource]) bool {
+		return req.Resource.Status == "draft"
+	})
+	cfg := &baccess.Config{Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"read:isDraft"}}}}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[*countingSubject, auth_test_utils.MockResource](), registry)
+	require.NoError(t, err)
+
+	var roleChecks atomic.Int64
+	counted := &countingSubject{MockSubject: subject, calls: &roleChecks}
+	assert.Len(t, evaluator.Filter(counted, "read", resources), 14)
+	assert.Equal(t, int64(1), roleChecks.Load())
+
+	// FilterSeq stops reading resources once the consumer stops.
+	for _, opts := range [][]baccess.FilterOption{nil, {baccess.WithParallelism(2)}} {
+		read := 0
+		source := func(yield func(auth_test_utils.MockResource) bool) {
+			for _, resource := range resources {
+				read++
+				if !yield(resource) {
+					return
+				}
+			}
+		}
+		var first []auth_test_utils.MockResource
+		for resource := range evaluator.FilterSeq(counted, "read", source, opts...) {
+			first = append(first, resource)
+			if len(first) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []string{"0", "3"}, []string{first[0].ID, first[1].ID})
+		assert.Less(t, read, len(resources))
+	}
+}
+
+func TestEvaluator_Filter_Environment(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	cfg := &baccess.Config{
+		Policies:   map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"edit:onNetwork"}}},
+		Conditions: map[string]string{"onNetwork": "env.network == 'corp'"},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), &MockPredicateProvider{})
+	require.NoError(t, err)
+	evaluator.AddDenyPolicy("edit", func(req request) bool {
+		return req.Environment != nil && req.Environment.GetAttribute("readOnly") == true
+	})
+
+	subject := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	resources := []auth_test_utils.MockResource{{ID: "1"}, {ID: "2"}}
+
+	for _, env := range []baccess.Environment{nil, {"network": "corp"}, {"network": "corp", "readOnly": true}} {
+		var want []auth_test_utils.MockResource
+		for _, resource := range resources {
+			req := request{Subject: subject, Resource: resource, Action: "edit"}
+			if env != nil {
+				req.Environment = env
+			}
+			if evaluator.Evaluate(req) {
+				want = append(want, resource)
+			}
+		}
+
+		var opts []baccess.FilterOption
+		if env != nil {
+			opts = append(opts, baccess.WithEnvironment(env))
+		}
+		assert.Equal(t, want, evaluator.Filter(subject, "edit", resources, opts...), env)
+		assert.Equal(t, want, slices.Collect(evaluator.FilterSeq(subject, "edit", slices.Values(resources), opts...)), env)
+	}
+
+	assert.Empty(t, evaluator.Filter(subject, "edit", resources))
+	assert.Len(t, evaluator.Filter(subject, "edit", resources, baccess.WithEnvironment(baccess.Environment{"network": "corp"})), 2)
+	assert.Empty(t, evaluator.Filter(subject, "edit", resources, baccess.WithEnvironment(baccess.Environment{"readOnly": true})))
+}
+
+type countingSubject struct {
+	auth_test_utils.MockSubject
+	calls *atomic.Int64
+}
+
+func (s *countingSubject) GetRoles() []string {
+	s.calls.Add(1)
+	return s.MockSubject.GetRoles()
+}
@@ -0,0 +1,247 @@
+// Package grpcauth provides gRPC server interceptors that authorize calls
+// with a baccess evaluator.
+//
+// For every call the interceptors identify the subject from the incoming
+// metadata, map the full method name to an action, load the resource and
+// evaluate the resulting AccessRequest. Calls that are not allowed fail
+// without reaching the handler:
+//
+//   - codes.Unauthenticated when the subject cannot be identified,
+//   - codes.PermissionDenied when access is denied or no action maps to the
+//     method, with an errdetails.ErrorInfo detail carrying the action (and,
+//     with Options.ExposeReason, the reason for the decision),
+//   - codes.Internal when loading the resource or evaluating fails, unless
+//     the resource loader returned a gRPC status error, which is passed on.
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brian-nunez/baccess"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the errdetails.ErrorInfo attached to
+// PermissionDenied errors.
+const ErrorDomain = "baccess"
+
+// Authorizer is implemented by *baccess.Evaluator and
+// *baccess.EvaluatorHolder.
+type Authorizer[S any, R any] interface {
+	DecideContext(ctx context.Context, req baccess.AccessRequest[S, R]) (baccess.Decision, error)
+}
+
+// Options configures the interceptors. Subject and Actions are required.
+type Options[S any, R any] struct {
+	// Subject identifies the caller from the incoming metadata, e.g. a
+	// bearer token. An error fails the call with codes.Unauthenticated.
+	Subject func(ctx context.Context, md metadata.MD) (S, error)
+	// Actions maps full method names ("/pkg.Service/Method") to actions. A
+	// key of the form "/pkg.Service/*" covers every method of the service
+	// that is not listed explicitly.
+	Actions map[string]string
+	// Resource loads the resource a call targets. req is the request message
+	// of a unary call and nil for a streaming call, which is authorized
+	// before any message is received. When nil, calls are evaluated against
+	// the zero R.
+	Resource func(ctx context.Context, method string, req any) (R, error)
+	// Environment supplies AccessRequest.Environment, e.g. the peer address.
+	Environment func(ctx context.Context) baccess.Attributable
+	// Audit is called once per call with the outcome of authorization.
+	Audit func(Event[S, R])
+	// ExposeReason sends the reason for a denial, which names the deciding
+	// policy, to the client. By default clients only learn which action was
+	// denied; the reason is always available to Audit.
+	ExposeReason bool
+}
+
+// Event describes how an interceptor handled a call.
+type Event[S any, R any] struct {
+	Method   string
+	Subject  S
+	Resource R
+	Action   string
+	Allowed  bool
+	// Reason explains the decision once the subject was authorized or
+	// denied.
+	Reason string
+	// Code is the code the call failed with, or codes.OK when it was passed
+	// on to the handler.
+	Code codes.Code
+	// Err is the error that stopped the call, if any.
+	Err error
+}
+
+type subjectKey struct{}
+type resourceKey struct{}
+
+// SubjectFrom returns the subject authorized by an interceptor.
+func SubjectFrom[S any](ctx context.Context) (S, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(S)
+	return subject, ok
+}
+
+// ResourceFrom returns the resource loaded by an interceptor.
+func ResourceFrom[R any](ctx context.Context) (R, bool) {
+	resource, ok := ctx.Value(resourceKey{}).(R)
+	return resource, ok
+}
+
+// UnaryServerInterceptor authorizes unary calls.
+func UnaryServerInterceptor[S any, R any](authorizer Authorizer[S, R], opts Options[S, R]) grpc.UnaryServerInterceptor {
+	a := newAuthorizer(authorizer, opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes streaming calls when they are opened.
+func StreamServerInterceptor[S any, R any](authorizer Authorizer[S, R], opts Options[S, R]) grpc.StreamServerInterceptor {
+	a := newAuthorizer(authorizer, opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream exposes the authorized context to stream handlers.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+type authorizer[S any, R any] struct {
+	evaluator Authorizer[S, R]
+	opts      Options[S, R]
+}
+
+func newAuthorizer[S any, R any](evaluator Authorizer[S, R], opts Options[S, R]) *authorizer[S, R] {
+	if opts.Subject == nil {
+		panic("grpcauth: Options.Subject is required")
+	}
+	if opts.Actions == nil {
+		panic("grpcauth: Options.Actions is required")
+	}
+
+	return &authorizer[S, R]{evaluator: evaluator, opts: opts}
+}
+
+// action looks up method, falling back to its service wildcard.
+func (a *authorizer[S, R]) action(method string) (string, bool) {
+	if action, ok := a.opts.Actions[method]; ok {
+		return action, true
+	}
+	if i := strings.LastIndexByte(method, '/'); i > 0 {
+		action, ok := a.opts.Actions[method[:i]+"/*"]
+		return action, ok
+	}
+
+	return "", false
+}
+
+// authorize returns the context to pass to the handler, or the error to fail
+// the call with.
+func (a *authorizer[S, R]) authorize(ctx context.Context, method string, msg any) (context.Context, error) {
+	event := Event[S, R]{Method: method}
+	reject := func(err error, cause error) (context.Context, error) {
+		event.Code, event.Err = status.Code(err), cause
+		if a.opts.Audit != nil {
+			a.opts.Audit(event)
+		}
+		return nil, err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	subject, err := a.opts.Subject(ctx, md)
+	if err != nil {
+		return reject(status.Error(codes.Unauthenticated, "authentication required"), err)
+	}
+	event.Subject = subject
+
+	action, ok := a.action(method)
+	if !ok {
+		return reject(permissionDenied("", "no action is defined for this method"), fmt.Errorf("no action mapped for method %s", method))
+	}
+	event.Action = action
+
+	var resource R
+	if a.opts.Resource != nil {
+		if resource, err = a.opts.Resource(ctx, method, msg); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return reject(err, err)
+			}
+			return reject(status.Error(codes.Internal, "failed to load resource"), err)
+		}
+	}
+	event.Resource = resource
+
+	req := baccess.AccessRequest[S, R]{Subject: subject, Resource: resource, Action: action}
+	if a.opts.Environment != nil {
+		req.Environment = a.opts.Environment(ctx)
+	}
+
+	decision, err := a.evaluator.DecideContext(ctx, req)
+	event.Reason = decision.Reason
+	if err != nil {
+		return reject(status.Error(codes.Internal, "authorization failed"), err)
+	}
+	if !decision.Allowed {
+		if a.opts.ExposeReason {
+			return reject(permissionDenied(action, decision.Reason), nil)
+		}
+		return reject(permissionDenied(action, ""), nil)
+	}
+
+	event.Allowed = true
+	if a.opts.Audit != nil {
+		a.opts.Audit(event)
+	}
+
+	ctx = context.WithValue(ctx, subjectKey{}, subject)
+	return context.WithValue(ctx, resourceKey{}, resource), nil
+}
+
+// permissionDenied builds a PermissionDenied status. Without a reason the
+// message only names the action.
+func permissionDenied(action, reason string) error {
+	metadata := map[string]string{"action": action}
+	message := reason
+	if reason != "" {
+		metadata["reason"] = reason
+	} else {
+		message = fmt.Sprintf("action %q is not permitted", action)
+	}
+
+	st := status.New(codes.PermissionDenied, message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   "ACCESS_DENIED",
+		Domain:   ErrorDomain,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,255 @@
+package grpcauth_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	"github.com/brian-nunez/baccess/grpcauth"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type (
+	subject  = auth_test_utils.MockSubject
+	resource = auth_test_utils.MockResource
+	request  = baccess.AccessRequest[subject, resource]
+	event    = grpcauth.Event[subject, resource]
+)
+
+// newClient serves the health service behind the interceptors over an
+// in-memory connection. configure adjusts the interceptor options.
+func newClient(t *testing.T, audit func(event), configure ...func(*grpcauth.Options[subject, resource])) (healthpb.HealthClient, *[]subject) {
+	rbac := baccess.NewRBAC[subject, resource]()
+	evaluator := baccess.NewEvaluator[subject, resource]()
+	evaluator.AddPolicy("read", rbac.HasRole("reader"))
+	evaluator.AddPolicy("watch", rbac.HasRole("admin"))
+	evaluator.AddDenyPolicy("*", func(req request) bool { return req.Resource.Status == "restricted" })
+	// Suspended callers are recognized from the RPC's own context.
+	evaluator.AddContextDenyPolicy("read", func(ctx context.Context, req request) (bool, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return len(md.Get("x-suspended")) > 0, nil
+	})
+
+	opts := grpcauth.Options[subject, resource]{
+		Subject: func(ctx context.Context, md metadata.MD) (subject, error) {
+			users := md.Get("x-user")
+			if len(users) == 0 {
+				return subject{}, errors.New("missing x-user")
+			}
+			return subject{ID: users[0], Roles: md.Get("x-role")}, nil
+		},
+		Actions: map[string]string{
+			healthpb.Health_Check_FullMethodName: "read",
+			"/grpc.health.v1.Health/*":           "watch",
+		},
+		Resource: func(ctx context.Context, method string, req any) (resource, error) {
+			check, ok := req.(*healthpb.HealthCheckRequest)
+			if !ok {
+				return resource{}, nil
+			}
+			switch check.Service {
+			case "missing":
+				return resource{}, status.Error(codes.NotFound, "unknown service")
+			case "broken":
+				return resource{}, errors.New("database unavailable")
+			case "secret":
+				return resource{ID: check.Service, Status: "restricted"}, nil
+			}
+			return resource{ID: check.Service}, nil
+		},
+		Audit: audit,
+	}
+	for _, fn := range configure {
+		fn(&opts)
+	}
+
+	// seen records the subjects that reached the handlers.
+	var seen []subject
+	record := func(ctx context.Context) {
+		s, ok := grpcauth.SubjectFrom[subject](ctx)
+		require.True(t, ok)
+		seen = append(seen, s)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			grpcauth.UnaryServerInterceptor(evaluator, opts),
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				record(ctx)
+				return handler(ctx, req)
+			},
+		),
+		grpc.ChainStreamInterceptor(
+			grpcauth.StreamServerInterceptor(evaluator, opts),
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				record(ss.Context())
+				return handler(srv, ss)
+			},
+		),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("docs", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("secret", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn), &seen
+}
+
+func withUser(user string, roles ...string) context.Context {
+	md := metadata.Pairs("x-user", user)
+	for _, role := range roles {
+		md.Append("x-role", role)
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var events []event
+	client, seen := newClient(t, func(e event) { events = append(events, e) })
+
+	resp, err := client.Check(withUser("alice", "reader"), &healthpb.HealthCheckRequest{Service: "docs"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	assert.Equal(t, []subject{{ID: "alice", Roles: []string{"reader"}}}, *seen)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		service  string
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{"unauthenticated", context.Background(), "docs", codes.Unauthenticated, "authentication required"},
+		{"missing role", withUser("bob"), "docs", codes.PermissionDenied, `action "read" is not permitted`},
+		{"denied", withUser("alice", "reader"), "secret", codes.PermissionDenied, `action "read" is not permitted`},
+		{"status from loader", withUser("alice", "reader"), "missing", codes.NotFound, "unknown service"},
+		{"loader fails", withUser("alice", "reader"), "broken", codes.Internal, "failed to load resource"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Check(tt.ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			st := status.Convert(err)
+			assert.Equal(t, tt.wantCode, st.Code())
+			assert.Equal(t, tt.wantMsg, st.Message())
+		})
+	}
+	assert.Len(t, *seen, 1)
+
+	// PermissionDenied carries the action and reason as an ErrorInfo detail.
+	_, err = client.Check(withUser("bob"), &healthpb.HealthCheckRequest{Service: "docs"})
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "ACCESS_DENIED", info.Reason)
+	assert.Equal(t, grpcauth.ErrorDomain, info.Domain)
+	assert.Equal(t, map[string]string{"action": "read"}, info.Metadata)
+
+	require.Len(t, events, 7)
+	assert.True(t, events[0].Allowed)
+	assert.Equal(t, codes.OK, events[0].Code)
+	assert.Equal(t, healthpb.Health_Check_FullMethodName, events[0].Method)
+	assert.Equal(t, "read", events[0].Action)
+	assert.Equal(t, "docs", events[0].Resource.ID)
+	assert.EqualError(t, events[1].Err, "missing x-user")
+	assert.Equal(t, codes.Unauthenticated, events[1].Code)
+	assert.Equal(t, codes.PermissionDenied, events[2].Code)
+	assert.NoError(t, events[2].Err)
+	assert.Equal(t, `no allow policy for action "read" is satisfied`, events[2].Reason)
+	assert.Equal(t, `denied by deny policy "*"`, events[3].Reason)
+	assert.EqualError(t, events[5].Err, "database unavailable")
+}
+
+func TestUnaryServerInterceptor_ExposeReason(t *testing.T) {
+	var events []event
+	client, _ := newClient(t, func(e event) { events = append(events, e) }, func(opts *grpcauth.Options[subject, resource]) {
+		opts.ExposeReason = true
+	})
+
+	_, err := client.Check(withUser("bob"), &healthpb.HealthCheckRequest{Service: "docs"})
+	assert.Equal(t, `no allow policy for action "read" is satisfied`, status.Convert(err).Message())
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	assert.Equal(t, map[string]string{"action": "read", "reason": `no allow policy for action "read" is satisfied`}, details[0].(*errdetails.ErrorInfo).Metadata)
+
+	// The reason comes from the evaluation that decided the call, which saw
+	// the RPC's context.
+	ctx := metadata.AppendToOutgoingContext(withUser("alice", "reader"), "x-suspended", "true")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "docs"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, `denied by deny policy "read"`, status.Convert(err).Message())
+	require.Len(t, events, 2)
+	assert.Equal(t, `denied by deny policy "read"`, events[1].Reason)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	client, seen := newClient(t, nil)
+
+	ctx, cancel := context.WithCancel(withUser("root", "admin"))
+	defer cancel()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "docs"})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	assert.Equal(t, []subject{{ID: "root", Roles: []string{"admin"}}}, *seen)
+
+	// Streams are authorized when opened, so the error arrives with the
+	// first Recv.
+	stream, err = client.Watch(withUser("alice", "reader"), &healthpb.HealthCheckRequest{Service: "docs"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), `action "watch"`)
+	assert.Len(t, *seen, 1)
+}
+
+func TestInterceptors_UnmappedMethod(t *testing.T) {
+	holder := baccess.NewEvaluatorHolder[subject, resource](nil)
+	interceptor := grpcauth.UnaryServerInterceptor(holder, grpcauth.Options[subject, resource]{
+		Subject: func(ctx context.Context, md metadata.MD) (subject, error) { return subject{ID: "alice"}, nil },
+		Actions: map[string]string{"/docs.Documents/Get": "read"},
+	})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/docs.Documents/Delete"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "no action is defined for this method", status.Convert(err).Message())
+
+	// Without a published evaluator evaluation fails.
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/docs.Documents/Get"}, handler)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	assert.PanicsWithValue(t, "grpcauth: Options.Actions is required", func() {
+		grpcauth.StreamServerInterceptor(holder, grpcauth.Options[subject, resource]{
+			Subject: func(ctx context.Context, md metadata.MD) (subject, error) { return subject{}, nil },
+		})
+	})
+}
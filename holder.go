@@ -0,0 +1,101 @@
+package baccess
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// EvaluatorHolder publishes an Evaluator that can be replaced while requests
+// are being evaluated. Readers always see either the previous or the new
+// evaluator in full; a reload never pauses traffic.
+type EvaluatorHolder[S any, R any] struct {
+	current atomic.Pointer[Evaluator[S, R]]
+}
+
+func NewEvaluatorHolder[S any, R any](e *Evaluator[S, R]) *EvaluatorHolder[S, R] {
+	h := &EvaluatorHolder[S, R]{}
+	if e != nil {
+		e.Compile()
+		h.current.Store(e)
+	}
+
+	return h
+}
+
+// Load returns the currently published evaluator, or nil if none was published.
+func (h *EvaluatorHolder[S, R]) Load() *Evaluator[S, R] {
+	return h.current.Load()
+}
+
+// Swap compiles and publishes e, returning the previously published evaluator.
+// Swapping in nil unpublishes the current evaluator, so that requests are
+// denied until another one is published.
+func (h *EvaluatorHolder[S, R]) Swap(e *Evaluator[S, R]) *Evaluator[S, R] {
+	if e != nil {
+		e.Compile()
+	}
+
+	return h.current.Swap(e)
+}
+
+// Reload builds a new evaluator with build and publishes it. If build returns
+// an error the current evaluator is kept and the error is returned, so a
+// broken policy never replaces a working one.
+func (h *EvaluatorHolder[S, R]) Reload(build func() (*Evaluator[S, R], error)) error {
+	e, err := build()
+	if err != nil {
+		return err
+	}
+	if e == nil {
+		return errors.New("reload produced no evaluator")
+	}
+	h.Swap(e)
+
+	return nil
+}
+
+// Evaluate evaluates req against the currently published evaluator. It denies
+// access if no evaluator has been published.
+func (h *EvaluatorHolder[S, R]) Evaluate(req AccessRequest[S, R]) bool {
+	e := h.current.Load()
+	if e == nil {
+		return false
+	}
+
+	return e.Evaluate(req)
+}
+
+// EvaluateContext evaluates req against the currently published evaluator
+// with Evaluator.EvaluateContext. It denies access with an error if no
+// evaluator has been published.
+func (h *EvaluatorHolder[S, R]) EvaluateContext(ctx context.Context, req AccessRequest[S, R]) (bool, error) {
+	e := h.current.Load()
+	if e == nil {
+		return false, errors.New("no evaluator loaded")
+	}
+
+	return e.EvaluateContext(ctx, req)
+}
+
+// DecideContext decides req against the currently published evaluator with
+// Evaluator.DecideContext. It denies access with an error if no evaluator has
+// been published.
+func (h *EvaluatorHolder[S, R]) DecideContext(ctx context.Context, req AccessRequest[S, R]) (Decision, error) {
+	e := h.current.Load()
+	if e == nil {
+		return Decision{Action: req.Action, Effect: EffectDeny, Reason: "no evaluator loaded"}, errors.New("no evaluator loaded")
+	}
+
+	return e.DecideContext(ctx, req)
+}
+
+// Explain explains req against the currently published evaluator.
+func (h *EvaluatorHolder[S, R]) Explain(req AccessRequest[S, R]) Decision {
+	e := h.current.Load()
+	if e == nil {
+		return Decision{Action: req.Action, Effect: EffectDeny, Reason: "no evaluator loaded"}
+	}
+
+	return e.Explain(req)
+}
@@ -0,0 +1,157 @@
+package baccess_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorHolder(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+	build := func(actions ...string) func() (*baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource], error) {
+		return func() (*baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource], error) {
+			cfg := &baccess.Config{Policies: map[string]baccess.RolePolicyConfig{"user": {Allow: actions}}}
+			return baccess.BuildEvaluator(cfg, rbac, provider)
+		}
+	}
+	req := func(action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+			Subject: auth_test_utils.MockSubject{Roles: []string{"user"}},
+			Action:  action,
+		}
+	}
+
+	holder := baccess.NewEvaluatorHolder[auth_test_utils.MockSubject, auth_test_utils.MockResource](nil)
+	assert.Nil(t, holder.Load())
+	assert.False(t, holder.Evaluate(req("read")))
+	assert.Equal(t, "no evaluator loaded", holder.Explain(req("read")).Reason)
+
+	assert.NoError(t, holder.Reload(build("read")))
+	assert.True(t, holder.Evaluate(req("read")))
+	assert.False(t, holder.Evaluate(req("write")))
+
+	previous := holder.Load()
+	assert.NoError(t, holder.Reload(build("write")))
+	assert.False(t, holder.Evaluate(req("read")))
+	assert.True(t, holder.Evaluate(req("write")))
+	assert.True(t, previous.Evaluate(req("read")))
+
+	// A failing build keeps the last good evaluator.
+	err := holder.Reload(build("write:missing"))
+	assert.Error(t, err)
+	assert.True(t, holder.Evaluate(req("write")))
+
+	err = holder.Reload(func() (*baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource], error) {
+		return nil, errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	old := holder.Swap(baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	assert.NotNil(t, old)
+	assert.False(t, holder.Evaluate(req("write")))
+
+	current := holder.Load()
+	assert.Same(t, current, holder.Swap(nil))
+	assert.Nil(t, holder.Load())
+	assert.False(t, holder.Evaluate(req("write")))
+}
+
+// The tests below are meant to be run with -race.
+
+func TestEvaluatorHolder_ConcurrentReload(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+	configs := []*baccess.Config{
+		{Policies: map[string]baccess.RolePolicyConfig{"user": {Allow: []string{"read"}}}},
+		{Policies: map[string]baccess.RolePolicyConfig{"user": {Allow: []string{"read", "write"}}}},
+	}
+
+	initial, err := baccess.BuildEvaluator(configs[0], rbac, provider)
+	assert.NoError(t, err)
+	holder := baccess.NewEvaluatorHolder(initial)
+
+	req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockSubject{Roles: []string{"user"}},
+		Action:  "read",
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 8 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if !holder.Evaluate(req) {
+					t.Error("read must be allowed by every published config")
+					return
+				}
+			}
+		})
+	}
+
+	for i := range 200 {
+		cfg := configs[i%len(configs)]
+		assert.NoError(t, holder.Reload(func() (*baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource], error) {
+			return baccess.BuildEvaluator(cfg, rbac, provider)
+		}))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestEvaluator_ConcurrentAddPolicy(t *testing.T) {
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	evaluator.AddPolicy("read", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{Action: "read"}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 500 {
+				assert.True(t, evaluator.Evaluate(req))
+				evaluator.Explain(req)
+			}
+		})
+	}
+	wg.Go(func() {
+		for range 500 {
+			evaluator.AddPolicy("write", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+		}
+	})
+	wg.Wait()
+}
+
+func TestRegistryAndRBAC_Concurrent(t *testing.T) {
+	registry := baccess.NewRegistry[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	isEditor := rbac.HasRole("editor")
+	req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockSubject{Roles: []string{"admin"}},
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 500 {
+			registry.Register("isOwner", isOwner())
+			rbac.Inherit("admin", "editor")
+		}
+	})
+	wg.Go(func() {
+		for range 500 {
+			_, _ = registry.GetPredicate("isOwner")
+			isEditor.IsSatisfiedBy(req)
+		}
+	})
+	wg.Wait()
+
+	assert.True(t, isEditor.IsSatisfiedBy(req))
+}
@@ -0,0 +1,113 @@
+package httpauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ActionFunc derives the action to authorize from a request. An error means
+// the request has no action and is rejected.
+type ActionFunc func(r *http.Request) (string, error)
+
+// DefaultMethods maps the common HTTP methods to CRUD actions.
+var DefaultMethods = map[string]string{
+	http.MethodGet:    "read",
+	http.MethodHead:   "read",
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// Methods derives the action from the request method alone.
+func Methods(methods map[string]string) ActionFunc {
+	return func(r *http.Request) (string, error) {
+		if action, ok := methods[r.Method]; ok {
+			return action, nil
+		}
+		return "", fmt.Errorf("no action mapped for method %s", r.Method)
+	}
+}
+
+// Routes derives the action from method and route, with routes keyed by
+// net/http ServeMux patterns:
+//
+//	httpauth.Routes(map[string]string{
+//		"GET /documents/{id}":        "read",
+//		"PUT /documents/{id}":        "update",
+//		"POST /documents/{id}/share": "share",
+//	})
+//
+// Patterns are matched with ServeMux precedence, so the middleware can wrap a
+// whole mux as well as individual handlers. The wildcards of the matched
+// pattern are set on the request with SetPathValue, so that r.PathValue works
+// in the Resource and Environment loaders either way. Routes panics if a
+// pattern is invalid or conflicts with another, like ServeMux.Handle.
+func Routes(routes map[string]string) ActionFunc {
+	mux := http.NewServeMux()
+	for pattern, action := range routes {
+		names := wildcards(pattern)
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			match := w.(*routeMatch)
+			match.action, match.ok = action, true
+			for _, name := range names {
+				match.values = append(match.values, [2]string{name, r.PathValue(name)})
+			}
+		})
+	}
+
+	return func(r *http.Request) (string, error) {
+		// ServeHTTP records its match on the request it routes, which must not
+		// replace the match of a mux serving r, so a copy is routed instead.
+		routed := *r
+		match := &routeMatch{}
+		mux.ServeHTTP(match, &routed)
+		if !match.ok {
+			return "", fmt.Errorf("no action mapped for %s %s", r.Method, r.URL.Path)
+		}
+
+		for _, value := range match.values {
+			r.SetPathValue(value[0], value[1])
+		}
+		return match.action, nil
+	}
+}
+
+// routeMatch is the ResponseWriter through which the handlers of the Routes
+// mux report the route that matched. Anything written to it, such as the
+// redirects ServeMux issues for non-canonical paths, is discarded.
+type routeMatch struct {
+	action string
+	ok     bool
+	values [][2]string
+	header http.Header
+}
+
+func (m *routeMatch) Header() http.Header {
+	if m.header == nil {
+		m.header = http.Header{}
+	}
+	return m.header
+}
+
+func (m *routeMatch) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (m *routeMatch) WriteHeader(int) {}
+
+// wildcards returns the names of the wildcards in the path of a ServeMux
+// pattern, e.g. "id" and "rest" for "GET /files/{id}/{rest...}".
+func wildcards(pattern string) []string {
+	var names []string
+	_, path, _ := strings.Cut(pattern, "/")
+	for segment := range strings.SplitSeq(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok && name != "$}" {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,178 @@
+// Package httpauth provides net/http middleware that authorizes requests with
+// a baccess evaluator.
+//
+// For every request the middleware identifies the subject, loads the
+// resource, derives the action from the method and route, and evaluates the
+// resulting AccessRequest. Requests that are not allowed are answered with a
+// JSON problem body (RFC 9457) and never reach the wrapped handler:
+//
+//   - 401 Unauthorized when the subject cannot be identified,
+//   - 404 Not Found when the resource loader returns ErrNotFound,
+//   - 403 Forbidden when access is denied or no action maps to the route,
+//   - 500 Internal Server Error when loading the resource or evaluating fails.
+package httpauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/brian-nunez/baccess"
+)
+
+// ErrNotFound may be returned (or wrapped) by a resource loader to answer
+// with 404 instead of 500.
+var ErrNotFound = errors.New("resource not found")
+
+// Authorizer is implemented by *baccess.Evaluator and
+// *baccess.EvaluatorHolder.
+type Authorizer[S any, R any] interface {
+	EvaluateContext(ctx context.Context, req baccess.AccessRequest[S, R]) (bool, error)
+}
+
+// Options configures Middleware. Subject is required.
+type Options[S any, R any] struct {
+	// Subject identifies the caller, e.g. from a session or bearer token. An
+	// error is answered with 401.
+	Subject func(r *http.Request) (S, error)
+	// Resource loads the resource the request targets. When nil, requests are
+	// evaluated against the zero R.
+	Resource func(r *http.Request) (R, error)
+	// Action derives the action from the request; it defaults to
+	// Methods(DefaultMethods).
+	Action ActionFunc
+	// Environment supplies AccessRequest.Environment, e.g. the client IP.
+	Environment func(r *http.Request) baccess.Attributable
+	// Audit is called once per request with the outcome of authorization.
+	Audit func(Event[S, R])
+}
+
+// Event describes how the middleware handled a request.
+type Event[S any, R any] struct {
+	Request  *http.Request
+	Subject  S
+	Resource R
+	Action   string
+	Allowed  bool
+	// Status is the status the middleware responded with, or 0 when the
+	// request was passed on to the wrapped handler.
+	Status int
+	// Err is the error that stopped the request, if any.
+	Err error
+}
+
+type subjectKey struct{}
+type resourceKey struct{}
+
+// Middleware returns middleware that only passes on requests the authorizer
+// allows. The subject and resource are available to the wrapped handler via
+// SubjectFrom and ResourceFrom.
+func Middleware[S any, R any](authorizer Authorizer[S, R], opts Options[S, R]) func(http.Handler) http.Handler {
+	if opts.Subject == nil {
+		panic("httpauth: Options.Subject is required")
+	}
+	action := opts.Action
+	if action == nil {
+		action = Methods(DefaultMethods)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event := Event[S, R]{Request: r}
+			reject := func(status int, detail string, err error) {
+				event.Status, event.Err = status, err
+				if opts.Audit != nil {
+					opts.Audit(event)
+				}
+				WriteProblem(w, status, detail)
+			}
+
+			subject, err := opts.Subject(r)
+			if err != nil {
+				reject(http.StatusUnauthorized, "authentication required", err)
+				return
+			}
+			event.Subject = subject
+
+			act, err := action(r)
+			if err != nil {
+				reject(http.StatusForbidden, "no action is defined for this request", err)
+				return
+			}
+			event.Action = act
+
+			var resource R
+			if opts.Resource != nil {
+				if resource, err = opts.Resource(r); err != nil {
+					if errors.Is(err, ErrNotFound) {
+						reject(http.StatusNotFound, "resource not found", err)
+					} else {
+						reject(http.StatusInternalServerError, "failed to load resource", err)
+					}
+					return
+				}
+			}
+			event.Resource = resource
+
+			req := baccess.AccessRequest[S, R]{Subject: subject, Resource: resource, Action: act}
+			if opts.Environment != nil {
+				req.Environment = opts.Environment(r)
+			}
+
+			allowed, err := authorizer.EvaluateContext(r.Context(), req)
+			if err != nil {
+				reject(http.StatusInternalServerError, "authorization failed", err)
+				return
+			}
+			if !allowed {
+				reject(http.StatusForbidden, fmt.Sprintf("action %q is not permitted", act), nil)
+				return
+			}
+
+			event.Allowed = true
+			if opts.Audit != nil {
+				opts.Audit(event)
+			}
+
+			ctx := context.WithValue(r.Context(), subjectKey{}, subject)
+			ctx = context.WithValue(ctx, resourceKey{}, resource)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// SubjectFrom returns the subject authorized by Middleware.
+func SubjectFrom[S any](ctx context.Context) (S, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(S)
+	return subject, ok
+}
+
+// ResourceFrom returns the resource loaded by Middleware, so handlers do not
+// need to load it again.
+func ResourceFrom[R any](ctx context.Context) (R, bool) {
+	resource, ok := ctx.Value(resourceKey{}).(R)
+	return resource, ok
+}
+
+// Problem is the JSON body of a rejected request.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// WriteProblem writes status with an application/problem+json body.
+func WriteProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,202 @@
+package httpauth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	"github.com/brian-nunez/baccess/httpauth"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+var documents = map[string]auth_test_utils.MockResource{
+	"1": {ID: "1", OwnerID: "alice"},
+	"2": {ID: "2", OwnerID: "bob"},
+}
+
+// newServer serves the documents behind the middleware, which wraps either
+// each route or, with wholeMux, the mux itself.
+func newServer(t *testing.T, wholeMux bool, audit func(httpauth.Event[auth_test_utils.MockSubject, auth_test_utils.MockResource])) *httptest.Server {
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	evaluator.AddPolicy("read", func(req request) bool { return true })
+	evaluator.AddPolicy("update", func(req request) bool { return req.Resource.OwnerID == req.Subject.ID })
+	evaluator.AddPolicy("share", func(req request) bool {
+		return req.Resource.OwnerID == req.Subject.ID && req.Environment.GetAttribute("ip") == "10.0.0.1"
+	})
+
+	authorize := httpauth.Middleware(evaluator, httpauth.Options[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Subject: func(r *http.Request) (auth_test_utils.MockSubject, error) {
+			user := r.Header.Get("X-User")
+			if user == "" {
+				return auth_test_utils.MockSubject{}, errors.New("missing X-User header")
+			}
+			return auth_test_utils.MockSubject{ID: user}, nil
+		},
+		Resource: func(r *http.Request) (auth_test_utils.MockResource, error) {
+			if r.PathValue("id") == "broken" {
+				return auth_test_utils.MockResource{}, errors.New("database unavailable")
+			}
+			doc, ok := documents[r.PathValue("id")]
+			if !ok {
+				return doc, fmt.Errorf("document %q: %w", r.PathValue("id"), httpauth.ErrNotFound)
+			}
+			return doc, nil
+		},
+		Action: httpauth.Routes(map[string]string{
+			"GET /documents/{id}":        "read",
+			"PUT /documents/{id}":        "update",
+			"POST /documents/{id}/share": "share",
+		}),
+		Environment: func(r *http.Request) baccess.Attributable {
+			return baccess.Environment{"ip": r.Header.Get("X-Forwarded-For")}
+		},
+		Audit: audit,
+	})
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		subject, ok := httpauth.SubjectFrom[auth_test_utils.MockSubject](r.Context())
+		require.True(t, ok)
+		doc, ok := httpauth.ResourceFrom[auth_test_utils.MockResource](r.Context())
+		require.True(t, ok)
+		fmt.Fprintf(w, "%s %s", subject.ID, doc.ID)
+	}
+	mux := http.NewServeMux()
+	if wholeMux {
+		mux.HandleFunc("/documents/{id}", handler)
+		mux.HandleFunc("/documents/{id}/share", handler)
+	} else {
+		mux.Handle("/documents/{id}", authorize(http.HandlerFunc(handler)))
+		mux.Handle("/documents/{id}/share", authorize(http.HandlerFunc(handler)))
+	}
+
+	var root http.Handler = mux
+	if wholeMux {
+		root = authorize(mux)
+	}
+	server := httptest.NewServer(root)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("per route", func(t *testing.T) { testMiddleware(t, false) })
+	// Routes sets the path values the loaders read before the mux has routed
+	// the request.
+	t.Run("whole mux", func(t *testing.T) { testMiddleware(t, true) })
+}
+
+func testMiddleware(t *testing.T, wholeMux bool) {
+	var events []httpauth.Event[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+	server := newServer(t, wholeMux, func(e httpauth.Event[auth_test_utils.MockSubject, auth_test_utils.MockResource]) {
+		events = append(events, e)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		ip         string
+		wantStatus int
+		wantBody   string
+	}{
+		{"allowed", http.MethodGet, "/documents/2", "alice", "", http.StatusOK, "alice 2"},
+		{"owner update", http.MethodPut, "/documents/1", "alice", "", http.StatusOK, "alice 1"},
+		{"environment", http.MethodPost, "/documents/1/share", "alice", "10.0.0.1", http.StatusOK, "alice 1"},
+		{"denied", http.MethodPut, "/documents/2", "alice", "", http.StatusForbidden, `action "update" is not permitted`},
+		{"denied by environment", http.MethodPost, "/documents/1/share", "alice", "192.168.0.1", http.StatusForbidden, `action "share" is not permitted`},
+		{"unauthenticated", http.MethodGet, "/documents/1", "", "", http.StatusUnauthorized, "authentication required"},
+		{"unmapped route", http.MethodDelete, "/documents/1", "alice", "", http.StatusForbidden, "no action is defined for this request"},
+		{"not found", http.MethodGet, "/documents/3", "alice", "", http.StatusNotFound, "resource not found"},
+		{"loader fails", http.MethodGet, "/documents/broken", "alice", "", http.StatusInternalServerError, "failed to load resource"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			require.NoError(t, err)
+			req.Header.Set("X-User", tt.user)
+			req.Header.Set("X-Forwarded-For", tt.ip)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantBody, string(body))
+				return
+			}
+
+			assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+			var problem httpauth.Problem
+			require.NoError(t, json.Unmarshal(body, &problem))
+			assert.Equal(t, httpauth.Problem{
+				Type:   "about:blank",
+				Title:  http.StatusText(tt.wantStatus),
+				Status: tt.wantStatus,
+				Detail: tt.wantBody,
+			}, problem)
+		})
+	}
+
+	require.Len(t, events, len(tests))
+	assert.True(t, events[0].Allowed)
+	assert.Equal(t, "read", events[0].Action)
+	assert.Equal(t, "alice", events[0].Subject.ID)
+	assert.Equal(t, "2", events[0].Resource.ID)
+	assert.Zero(t, events[0].Status)
+
+	assert.False(t, events[3].Allowed)
+	assert.Equal(t, http.StatusForbidden, events[3].Status)
+	assert.NoError(t, events[3].Err)
+
+	assert.EqualError(t, events[5].Err, "missing X-User header")
+	assert.EqualError(t, events[6].Err, "no action mapped for DELETE /documents/1")
+	assert.ErrorIs(t, events[7].Err, httpauth.ErrNotFound)
+}
+
+func TestMiddleware_Defaults(t *testing.T) {
+	holder := baccess.NewEvaluatorHolder[auth_test_utils.MockSubject, auth_test_utils.MockResource](nil)
+	authorize := httpauth.Middleware(holder, httpauth.Options[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Subject: func(r *http.Request) (auth_test_utils.MockSubject, error) {
+			return auth_test_utils.MockSubject{ID: "alice"}, nil
+		},
+	})
+	handler := authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Without a published evaluator evaluation fails.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/anything", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	evaluator.AddPolicy("read", func(req request) bool { return true })
+	holder.Swap(evaluator)
+
+	for method, want := range map[string]int{
+		http.MethodGet:     http.StatusOK,
+		http.MethodHead:    http.StatusOK,
+		http.MethodPost:    http.StatusForbidden,
+		http.MethodOptions: http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/anything", nil))
+		assert.Equal(t, want, rec.Code, method)
+	}
+
+	assert.PanicsWithValue(t, "httpauth: Options.Subject is required", func() {
+		httpauth.Middleware(holder, httpauth.Options[auth_test_utils.MockSubject, auth_test_utils.MockResource]{})
+	})
+}
@@ -0,0 +1,137 @@
+package baccess
+
+import (
+	"slices"
+	"strings"
+)
+
+// policyIndex is a compiled view of an Evaluator's policies. For every shape
+// of request action it precomputes the positions of the policies that match
+// under rules 1-5, in insertion order, so Evaluate only visits matching
+// policies and never re-parses policy keys.
+type policyIndex struct {
+	global []int32
+	bases  map[string]*baseIndex
+	// actions lists the base actions named by policy keys, for AllowedActions.
+	actions []string
+}
+
+type baseIndex struct {
+	// bare holds the candidates for a request without a condition whose
+	// action equals a plain policy key (e.g. "read").
+	bare map[string][]int32
+	// bareOther holds the candidates for any other request without a
+	// condition (e.g. "delete" when only "delete:isOwner" exists).
+	bareOther []int32
+	// withCondition holds the candidates for "base:condition" requests whose
+	// condition appears in a policy key.
+	withCondition map[string][]int32
+	// withOtherCondition holds the candidates for any other conditional request.
+	withOtherCondition []int32
+}
+
+// splitAction splits "base:condition" at the first colon.
+func splitAction(action string) (base, condition string) {
+	if colonIndex := strings.Index(action, ":"); colonIndex != -1 {
+		return action[:colonIndex], action[colonIndex+1:]
+	}
+
+	return action, ""
+}
+
+func compilePolicyIndex[S any, R any](policies []policy[S, R]) *policyIndex {
+	type buckets struct {
+		plain       map[string][]int32 // keyed by the full policy key
+		plainAll    []int32
+		wildcard    []int32
+		conditional map[string][]int32 // keyed by condition
+		condAll     []int32            // conditional and wildcard policies
+	}
+
+	idx := &policyIndex{bases: make(map[string]*baseIndex)}
+	byBase := make(map[string]*buckets)
+
+	for i, p := range policies {
+		pos := int32(i)
+		if p.key == "*" {
+			idx.global = append(idx.global, pos)
+			continue
+		}
+
+		base, condition := splitAction(p.key)
+		b, ok := byBase[base]
+		if !ok {
+			b = &buckets{plain: make(map[string][]int32), conditional: make(map[string][]int32)}
+			byBase[base] = b
+		}
+
+		switch condition {
+		case "":
+			b.plain[p.key] = append(b.plain[p.key], pos)
+			b.plainAll = append(b.plainAll, pos)
+		case "*":
+			b.wildcard = append(b.wildcard, pos)
+			b.condAll = append(b.condAll, pos)
+		default:
+			b.conditional[condition] = append(b.conditional[condition], pos)
+			b.condAll = append(b.condAll, pos)
+		}
+	}
+
+	merge := func(lists ...[]int32) []int32 {
+		merged := slices.Concat(lists...)
+		slices.Sort(merged)
+
+		return merged
+	}
+
+	for base, b := range byBase {
+		bi := &baseIndex{
+			bare:               make(map[string][]int32, len(b.plain)),
+			bareOther:          merge(idx.global, b.condAll),
+			withCondition:      make(map[string][]int32, len(b.conditional)),
+			withOtherCondition: merge(idx.global, b.wildcard, b.plainAll),
+		}
+		for key, exact := range b.plain {
+			bi.bare[key] = merge(idx.global, b.condAll, exact)
+		}
+		for condition, exact := range b.conditional {
+			bi.withCondition[condition] = merge(idx.global, b.wildcard, b.plainAll, exact)
+		}
+		idx.bases[base] = bi
+	}
+	idx.actions = namedActions(idx.bases)
+
+	return idx
+}
+
+func (idx *policyIndex) wildcards() []int32 {
+	return idx.global
+}
+
+func (idx *policyIndex) actionNames() []string {
+	return idx.actions
+}
+
+// candidates returns the positions of the policies matching action.
+func (idx *policyIndex) candidates(action string) []int32 {
+	base, condition := splitAction(action)
+
+	b, ok := idx.bases[base]
+	if !ok {
+		return idx.global
+	}
+
+	if condition == "" {
+		if list, ok := b.bare[action]; ok {
+			return list
+		}
+		return b.bareOther
+	}
+
+	if list, ok := b.withCondition[condition]; ok {
+		return list
+	}
+
+	return b.withOtherCondition
+}
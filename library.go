@@ -126,3 +126,49 @@ func SubjectAttrTrue[S Attributable, R any](key string) Predicate[AccessRequest[
 		return false
 	}
 }
+
+// envAttribute returns the environment attribute key, or nil when the request
+// has no environment.
+func envAttribute[S any, R any](req AccessRequest[S, R], key string) any {
+	if req.Environment == nil {
+		return nil
+	}
+
+	return req.Environment.GetAttribute(key)
+}
+
+func EnvAttrEquals[S any, R any](key string, val any) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		return envAttribute(req, key) == val
+	}
+}
+
+func EnvAttrGT[S any, R any](key string, threshold int) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		if v, ok := envAttribute(req, key).(int); ok {
+			return v > threshold
+		}
+
+		return false
+	}
+}
+
+func EnvAttrLT[S any, R any](key string, threshold int) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		if v, ok := envAttribute(req, key).(int); ok {
+			return v < threshold
+		}
+
+		return false
+	}
+}
+
+func EnvAttrTrue[S any, R any](key string) Predicate[AccessRequest[S, R]] {
+	return func(req AccessRequest[S, R]) bool {
+		if v, ok := envAttribute(req, key).(bool); ok {
+			return v
+		}
+
+		return false
+	}
+}
@@ -219,3 +219,23 @@ func TestSubjectAttrTrue(t *testing.T) {
 	predicate = baccess.SubjectAttrTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]("name") // string is not bool
 	assert.False(t, predicate.IsSatisfiedBy(req))
 }
+
+func TestEnvAttrPredicates(t *testing.T) {
+	req := baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Environment: baccess.Environment{"mfa": true, "ip": "10.0.0.1", "hour": 14},
+	}
+
+	assert.True(t, baccess.EnvAttrEquals[auth_test_utils.MockSubject, auth_test_utils.MockResource]("ip", "10.0.0.1").IsSatisfiedBy(req))
+	assert.False(t, baccess.EnvAttrEquals[auth_test_utils.MockSubject, auth_test_utils.MockResource]("ip", "10.0.0.2").IsSatisfiedBy(req))
+	assert.True(t, baccess.EnvAttrTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]("mfa").IsSatisfiedBy(req))
+	assert.False(t, baccess.EnvAttrTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]("ip").IsSatisfiedBy(req))
+	assert.True(t, baccess.EnvAttrGT[auth_test_utils.MockSubject, auth_test_utils.MockResource]("hour", 8).IsSatisfiedBy(req))
+	assert.False(t, baccess.EnvAttrLT[auth_test_utils.MockSubject, auth_test_utils.MockResource]("hour", 8).IsSatisfiedBy(req))
+	assert.False(t, baccess.EnvAttrGT[auth_test_utils.MockSubject, auth_test_utils.MockResource]("ip", 8).IsSatisfiedBy(req))
+
+	// A request without an environment satisfies none of them.
+	req.Environment = nil
+	assert.False(t, baccess.EnvAttrTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]("mfa").IsSatisfiedBy(req))
+	assert.True(t, baccess.EnvAttrEquals[auth_test_utils.MockSubject, auth_test_utils.MockResource]("mfa", nil).IsSatisfiedBy(req))
+	assert.False(t, baccess.EnvAttrLT[auth_test_utils.MockSubject, auth_test_utils.MockResource]("hour", 24).IsSatisfiedBy(req))
+}
@@ -0,0 +1,273 @@
+package baccess
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ActionMatcher decides which policies apply to a requested action.
+//
+// Match is given the policy's key as pattern: the key passed to AddPolicy and
+// its variants, or the full rule ("edit:isOwner") for policies built from a
+// Config, so that both kinds of policy match the same actions. The built-in
+// matchers interpret a ":condition" suffix on the pattern and the action
+// themselves.
+type ActionMatcher interface {
+	Match(pattern, action string) bool
+}
+
+// WithActionMatcher selects how policy keys are matched against requested
+// actions. The default is DefaultMatcher.
+func WithActionMatcher(matcher ActionMatcher) EvaluatorOption {
+	return func(o *evaluatorOptions) {
+		o.matcher = matcher
+	}
+}
+
+// DefaultMatcher applies the five matching rules of the policy index:
+//
+//  1. "*" matches every action.
+//  2. A key matches an identical action.
+//  3. "update:*" matches "update" and "update:<anything>".
+//  4. "read" matches "read:<anything>".
+//  5. "delete:isOwner" matches "delete".
+type DefaultMatcher struct{}
+
+func (DefaultMatcher) Match(pattern, action string) bool {
+	return matchAction(pattern, action) != 0
+}
+
+// StrictMatcher only applies a policy to the action it names, so "read" no
+// longer grants "read:anything". The global "*" still matches every action.
+//
+// A pattern with a ":condition" suffix also applies to the bare action, as
+// under rule 5 of DefaultMatcher: "delete:isOwner" matches "delete" and
+// "delete:isOwner" but not "delete:isAdmin", and "read:*" matches "read"
+// with any condition.
+type StrictMatcher struct{}
+
+func (StrictMatcher) Match(pattern, action string) bool {
+	if pattern == "*" {
+		return true
+	}
+	pattern, patternCondition := splitAction(pattern)
+	action, actionCondition := splitAction(action)
+
+	return (pattern == "*" || pattern == action) && conditionMatches(patternCondition, actionCondition)
+}
+
+// GlobMatcher treats dot-separated actions as paths: in a pattern, a "*"
+// segment matches exactly one segment and a "**" segment matches any number
+// of segments, including none. "documents.*.read" matches
+// "documents.42.read" but not "documents.42.pages.read", which
+// "documents.**.read" matches. A lone "*" still matches every action. A
+// ":condition" suffix is not part of the path and is matched as under
+// StrictMatcher.
+type GlobMatcher struct{}
+
+func (GlobMatcher) Match(pattern, action string) bool {
+	if pattern == "*" {
+		return true
+	}
+	pattern, patternCondition := splitAction(pattern)
+	action, actionCondition := splitAction(action)
+
+	return (pattern == "*" || matchGlob(pattern, action)) && conditionMatches(patternCondition, actionCondition)
+}
+
+// conditionMatches reports whether a pattern with the condition suffix
+// pattern applies to an action with the condition suffix action, where ""
+// means no suffix.
+func conditionMatches(pattern, action string) bool {
+	return action == "" || pattern == "*" || pattern == action
+}
+
+// HierarchyMatcher treats dot-separated actions as a tree in which a grant
+// covers everything below it: "billing.invoice" matches "billing.invoice"
+// and "billing.invoice.refund", and "billing.*" matches every action below
+// "billing" but not "billing" itself. A "*" segment matches any single
+// segment, and a lone "*" matches every action.
+//
+// A ":condition" suffix is not part of the tree, on the pattern or on the
+// action. When both carry one they must be equal, unless the pattern's is
+// "*": "billing.invoice:isOwner" matches "billing.invoice.read" and
+// "billing.invoice:isOwner" but not "billing.invoice:isAdmin".
+type HierarchyMatcher struct{}
+
+func (HierarchyMatcher) Match(pattern, action string) bool {
+	pattern, patternCondition := splitAction(pattern)
+	action, actionCondition := splitAction(action)
+	if patternCondition != "" && actionCondition != "" && patternCondition != "*" && patternCondition != actionCondition {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+
+	for pattern != "" {
+		if action == "" {
+			return false
+		}
+		segment, rest, _ := strings.Cut(pattern, ".")
+		actionSegment, actionRest, _ := strings.Cut(action, ".")
+		if segment != "*" && segment != actionSegment {
+			return false
+		}
+		pattern, action = rest, actionRest
+	}
+
+	return true
+}
+
+// ActionMatching names a built-in ActionMatcher, so that a Config can select
+// one.
+type ActionMatching string
+
+const (
+	DefaultMatching   ActionMatching = "default"
+	StrictMatching    ActionMatching = "strict"
+	GlobMatching      ActionMatching = "glob"
+	HierarchyMatching ActionMatching = "hierarchy"
+)
+
+// matcher returns the ActionMatcher m names, or nil if m is unknown.
+func (m ActionMatching) matcher() ActionMatcher {
+	switch m {
+	case DefaultMatching:
+		return DefaultMatcher{}
+	case StrictMatching:
+		return StrictMatcher{}
+	case GlobMatching:
+		return GlobMatcher{}
+	case HierarchyMatching:
+		return HierarchyMatcher{}
+	}
+
+	return nil
+}
+
+func matchGlob(pattern, action string) bool {
+	for pattern != "" {
+		segment, rest, _ := strings.Cut(pattern, ".")
+		if segment == "**" {
+			if rest == "" {
+				return true
+			}
+			for remaining := action; ; {
+				if matchGlob(rest, remaining) {
+					return true
+				}
+				var more bool
+				if _, remaining, more = strings.Cut(remaining, "."); !more {
+					return false
+				}
+			}
+		}
+
+		if action == "" {
+			return false
+		}
+		actionSegment, actionRest, _ := strings.Cut(action, ".")
+		if segment != "*" && segment != actionSegment {
+			return false
+		}
+		pattern, action = rest, actionRest
+	}
+
+	return action == ""
+}
+
+// actionIndex resolves the policies matching a requested action.
+type actionIndex interface {
+	// candidates returns the positions of the policies matching action, in
+	// insertion order.
+	candidates(action string) []int32
+	// wildcards returns the positions of the global "*" policies.
+	wildcards() []int32
+	// actionNames returns the sorted actions named by policy keys, for
+	// AllowedActions.
+	actionNames() []string
+}
+
+func compileActionIndex[S any, R any](matcher ActionMatcher, policies []policy[S, R]) actionIndex {
+	if matcher == nil {
+		return compilePolicyIndex(policies)
+	}
+
+	return compileMatcherIndex(matcher, policies)
+}
+
+// maxCachedActions bounds the number of distinct actions a matcherIndex
+// remembers, since actions may come from untrusted input.
+const maxCachedActions = 1024
+
+// matcherIndex serves matchers other than DefaultMatcher, whose patterns
+// cannot be bucketed ahead of time. The candidates of each action are found
+// by asking the matcher about every policy once and are then cached.
+type matcherIndex struct {
+	matcher  ActionMatcher
+	patterns []string
+	global   []int32
+	actions  []string
+
+	mu    sync.Mutex
+	cache atomic.Pointer[map[string][]int32]
+}
+
+func compileMatcherIndex[S any, R any](matcher ActionMatcher, policies []policy[S, R]) *matcherIndex {
+	// Under HierarchyMatcher a pattern such as "billing.*" names a subtree of
+	// actions, which AllowedActions reports as a whole.
+	_, hierarchical := matcher.(HierarchyMatcher)
+
+	idx := &matcherIndex{matcher: matcher, patterns: make([]string, len(policies))}
+	for i, p := range policies {
+		idx.patterns[i] = p.key
+		base, _ := splitAction(p.key)
+		if base == "*" {
+			idx.global = append(idx.global, int32(i))
+		} else if base != "" && (hierarchical || !strings.Contains(base, "*")) {
+			idx.actions = append(idx.actions, base)
+		}
+	}
+	slices.Sort(idx.actions)
+	idx.actions = slices.Compact(idx.actions)
+	idx.cache.Store(&map[string][]int32{})
+
+	return idx
+}
+
+func (idx *matcherIndex) candidates(action string) []int32 {
+	if list, ok := (*idx.cache.Load())[action]; ok {
+		return list
+	}
+
+	var list []int32
+	for i, pattern := range idx.patterns {
+		if idx.matcher.Match(pattern, action) {
+			list = append(list, int32(i))
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// The cache is copied on write so that lookups never take the lock.
+	if cache := *idx.cache.Load(); len(cache) < maxCachedActions {
+		updated := maps.Clone(cache)
+		updated[action] = list
+		idx.cache.Store(&updated)
+	}
+
+	return list
+}
+
+func (idx *matcherIndex) wildcards() []int32 {
+	return idx.global
+}
+
+func (idx *matcherIndex) actionNames() []string {
+	return idx.actions
+}
@@ -0,0 +1,316 @@
+package baccess_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		action  string
+		want    bool
+	}{
+		{"*", "documents.42.read", true},
+		{"**", "documents.42.read", true},
+		{"documents.read", "documents.read", true},
+		{"documents.read", "documents.write", false},
+		{"documents.*.read", "documents.42.read", true},
+		{"documents.*.read", "documents.42.pages.read", false},
+		{"documents.*.read", "documents.read", false},
+		{"documents.**.read", "documents.42.pages.read", true},
+		{"documents.**.read", "documents.read", true},
+		{"documents.**", "documents", true},
+		{"documents.**", "documents.42.read", true},
+		{"documents.**", "documentsx", false},
+		{"**.read", "read", true},
+		{"**.read", "documents.42.read", true},
+		{"**.read", "documents.42.write", false},
+		{"documents.*", "documents", false},
+		{"documents.*", "documents.42.read", false},
+		{"*.read", "read", false},
+		{"documents.*.read:isOwner", "documents.42.read", true},
+		{"documents.*.read:isOwner", "documents.42.read:isOwner", true},
+		{"documents.*.read:isOwner", "documents.42.read:isAdmin", false},
+		{"documents.*.read", "documents.42.read:isOwner", false},
+		{"documents.**:*", "documents.42.read:isOwner", true},
+		{"*:isOwner", "billing.read", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.action, func(t *testing.T) {
+			assert.Equal(t, tt.want, baccess.GlobMatcher{}.Match(tt.pattern, tt.action))
+		})
+	}
+}
+
+func TestEvaluator_ActionMatcher(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+	allow := alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+
+	newEvaluator := func(opts ...baccess.EvaluatorOption) *baccess.Evaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		evaluator := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](opts...)
+		evaluator.AddPolicy("read", allow)
+		evaluator.AddPolicy("delete:isOwner", allow)
+		return evaluator
+	}
+
+	// The default keeps matching rules 4 and 5.
+	evaluator := newEvaluator()
+	assert.True(t, evaluator.Evaluate(request{Action: "read:anything"}))
+	assert.True(t, evaluator.Evaluate(request{Action: "delete"}))
+
+	evaluator = newEvaluator(baccess.WithActionMatcher(baccess.DefaultMatcher{}))
+	assert.True(t, evaluator.Evaluate(request{Action: "read:anything"}))
+	assert.Equal(t, 4, evaluator.Explain(request{Action: "read:anything"}).Policies[0].MatchRule)
+
+	strict := newEvaluator(baccess.WithActionMatcher(baccess.StrictMatcher{}))
+	assert.True(t, strict.Evaluate(request{Action: "read"}))
+	assert.False(t, strict.Evaluate(request{Action: "read:anything"}))
+	assert.True(t, strict.Evaluate(request{Action: "delete"}))
+	assert.True(t, strict.Evaluate(request{Action: "delete:isOwner"}))
+	assert.False(t, strict.Evaluate(request{Action: "delete:isAdmin"}))
+	decision := strict.Explain(request{Action: "read"})
+	require.Len(t, decision.Policies, 1)
+	assert.Equal(t, "read", decision.Policies[0].Key)
+	assert.Zero(t, decision.Policies[0].MatchRule)
+
+	actions, everything := strict.AllowedActions(auth_test_utils.MockSubject{}, auth_test_utils.MockResource{})
+	assert.Equal(t, []string{"delete", "read"}, actions)
+	assert.False(t, everything)
+
+	// Any type can match actions, e.g. case-insensitively.
+	custom := newEvaluator(baccess.WithActionMatcher(caseInsensitiveMatcher{}))
+	assert.True(t, custom.Evaluate(request{Action: "READ"}))
+	assert.False(t, custom.Evaluate(request{Action: "write"}))
+
+	// Candidates are cached per action, up to a limit; beyond it they are
+	// still computed correctly.
+	for i := range 2000 {
+		assert.False(t, strict.Evaluate(request{Action: fmt.Sprintf("action%d", i)}))
+	}
+	assert.True(t, strict.Evaluate(request{Action: "read"}))
+	strict.AddPolicy("action1999", allow)
+	assert.True(t, strict.Evaluate(request{Action: "action1999"}))
+}
+
+type caseInsensitiveMatcher struct{}
+
+func (caseInsensitiveMatcher) Match(pattern, action string) bool {
+	return strings.EqualFold(pattern, action)
+}
+
+func TestStrictMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		action  string
+		want    bool
+	}{
+		{"*", "read:anything", true},
+		{"*:isOwner", "read", true},
+		{"read", "read", true},
+		{"read", "read:anything", false},
+		{"read", "reader", false},
+		{"read:*", "read", true},
+		{"read:*", "read:anything", true},
+		{"delete:isOwner", "delete", true},
+		{"delete:isOwner", "delete:isOwner", true},
+		{"delete:isOwner", "delete:isAdmin", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.action, func(t *testing.T) {
+			assert.Equal(t, tt.want, baccess.StrictMatcher{}.Match(tt.pattern, tt.action))
+		})
+	}
+}
+
+// Rules from a Config match the same actions as the same keys added with
+// AddPolicy, under every built-in matcher.
+func TestBuildEvaluator_ActionMatcher(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	rules := []string{"read", "delete:isOwner", "documents.*.edit:isOwner", "share:*"}
+	actions := []string{
+		"read", "read:anything", "delete", "delete:isOwner", "delete:isAdmin",
+		"documents.42.edit", "documents.42.edit:isOwner", "documents.42.edit:isAdmin", "share", "share:team",
+	}
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{"isOwner": isOwner()},
+	}
+	subject := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	owned := auth_test_utils.MockResource{OwnerID: "u1"}
+
+	for _, matching := range []baccess.ActionMatching{baccess.StrictMatching, baccess.GlobMatching} {
+		t.Run(string(matching), func(t *testing.T) {
+			cfg := &baccess.Config{
+				Matcher:  matching,
+				Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: rules}},
+			}
+			configured, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider)
+			require.NoError(t, err)
+
+			var options []baccess.EvaluatorOption
+			switch matching {
+			case baccess.StrictMatching:
+				options = append(options, baccess.WithActionMatcher(baccess.StrictMatcher{}))
+			case baccess.GlobMatching:
+				options = append(options, baccess.WithActionMatcher(baccess.GlobMatcher{}))
+			}
+			programmatic := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](options...)
+			for _, rule := range rules {
+				programmatic.AddPolicy(rule, isOwner())
+			}
+
+			for _, action := range actions {
+				req := request{Subject: subject, Resource: owned, Action: action}
+				assert.Equal(t, programmatic.Evaluate(req), configured.Evaluate(req), action)
+			}
+
+			req := func(action string) request { return request{Subject: subject, Resource: owned, Action: action} }
+			assert.True(t, configured.Evaluate(req("delete:isOwner")))
+			assert.True(t, configured.Evaluate(req("delete")))
+			assert.False(t, configured.Evaluate(req("delete:isAdmin")))
+			assert.False(t, configured.Evaluate(req("read:anything")))
+			assert.True(t, configured.Evaluate(req("share:team")))
+		})
+	}
+}
+
+func TestBuildEvaluator_GlobMatcher(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{
+			"isOwner": isOwner(),
+			"isDraft": isDraft(),
+		},
+	}
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"admin":  {Allow: []string{"*"}},
+			"editor": {Allow: []string{"documents.*.read", "documents.**:isOwner"}, Deny: []string{"documents.*.publish:isDraft"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider, baccess.WithActionMatcher(baccess.GlobMatcher{}))
+	require.NoError(t, err)
+
+	editor := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	owned := auth_test_utils.MockResource{OwnerID: "u1", Status: "published"}
+	other := auth_test_utils.MockResource{OwnerID: "u2", Status: "published"}
+	draft := auth_test_utils.MockResource{OwnerID: "u1", Status: "draft"}
+
+	assert.True(t, evaluator.Evaluate(request{Subject: editor, Resource: other, Action: "documents.42.read"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: editor, Resource: other, Action: "documents.42.pages.read"}))
+	// Conditions stay the suffix of the rule and are not part of the pattern.
+	assert.True(t, evaluator.Evaluate(request{Subject: editor, Resource: owned, Action: "documents.42.pages.read"}))
+	assert.True(t, evaluator.Evaluate(request{Subject: editor, Resource: owned, Action: "documents.42.publish"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: editor, Resource: draft, Action: "documents.42.publish"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: editor, Resource: owned, Action: "billing.read"}))
+
+	admin := auth_test_utils.MockSubject{Roles: []string{"admin"}}
+	assert.True(t, evaluator.Evaluate(request{Subject: admin, Action: "billing.invoices.42.refund"}))
+
+	decision := evaluator.Explain(request{Subject: editor, Resource: draft, Action: "documents.42.publish"})
+	assert.Equal(t, `denied by deny policy "documents.*.publish:isDraft" for role "editor"`, decision.Reason)
+}
+
+func TestHierarchyMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		action  string
+		want    bool
+	}{
+		{"*", "billing.invoice.read", true},
+		{"billing.invoice", "billing.invoice", true},
+		{"billing.invoice", "billing.invoice.read", true},
+		{"billing.invoice", "billing.invoice.line.update", true},
+		{"billing.invoice", "billing", false},
+		{"billing.invoice", "billing.invoices", false},
+		{"billing.invoice", "billing.receipt.read", false},
+		{"billing.*", "billing.invoice", true},
+		{"billing.*", "billing.invoice.refund", true},
+		{"billing.*", "billing", false},
+		{"billing.*.read", "billing.invoice.read", true},
+		{"billing.*.read", "billing.invoice.refund", false},
+		{"billing.invoice:isOwner", "billing.invoice.read", true},
+		{"billing:isOwner", "reports", false},
+		{"billing.invoice:isOwner", "billing.invoice:isOwner", true},
+		{"billing.invoice:isOwner", "billing.invoice.read:isOwner", true},
+		{"billing.invoice:isOwner", "billing.invoice:isAdmin", false},
+		{"billing.invoice:*", "billing.invoice:isAdmin", true},
+		{"billing.invoice", "billing.invoice:own", true},
+		{"billing.invoice", "billing.invoice.read:own", true},
+		{"billing.invoice", "billing.invoices:own", false},
+		{"*", "billing.invoice:own", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.action, func(t *testing.T) {
+			assert.Equal(t, tt.want, baccess.HierarchyMatcher{}.Match(tt.pattern, tt.action))
+		})
+	}
+}
+
+func TestBuildEvaluator_HierarchyMatcher(t *testing.T) {
+	type request = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[request]{
+			"isOwner": isOwner(),
+		},
+	}
+	cfg := &baccess.Config{
+		Matcher: baccess.HierarchyMatching,
+		Policies: map[string]baccess.RolePolicyConfig{
+			"accountant": {Allow: []string{"billing.invoice", "billing.receipt.read:isOwner"}, Deny: []string{"billing.invoice.void"}},
+			"auditor":    {Allow: []string{"billing.*"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider)
+	require.NoError(t, err)
+
+	accountant := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"accountant"}}
+	auditor := auth_test_utils.MockSubject{ID: "u2", Roles: []string{"auditor"}}
+	owned := auth_test_utils.MockResource{OwnerID: "u1"}
+
+	assert.True(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice.read"}))
+	assert.True(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice.refund"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice.void"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing"}))
+	assert.True(t, evaluator.Evaluate(request{Subject: accountant, Resource: owned, Action: "billing.receipt.read.pdf"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.receipt.read"}))
+	assert.True(t, evaluator.Evaluate(request{Subject: auditor, Action: "billing.invoice.void"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: auditor, Action: "billing"}))
+	// The condition suffix of a requested action is not part of the tree.
+	assert.True(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice:own"}))
+	assert.False(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice.void:own"}))
+
+	keyed := baccess.NewEvaluator[auth_test_utils.MockSubject, auth_test_utils.MockResource](baccess.WithActionMatcher(baccess.HierarchyMatcher{}))
+	keyed.AddPolicy("billing.invoice:isOwner", isOwner())
+	assert.True(t, keyed.Evaluate(request{Subject: accountant, Resource: owned, Action: "billing.invoice:isOwner"}))
+	assert.True(t, keyed.Evaluate(request{Subject: accountant, Resource: owned, Action: "billing.invoice.read"}))
+	assert.False(t, keyed.Evaluate(request{Subject: accountant, Resource: owned, Action: "billing.invoice:isAdmin"}))
+
+	// Subtrees are listed as a whole; denied actions below them are left out.
+	actions, everything := evaluator.AllowedActions(accountant, owned)
+	assert.Equal(t, []string{"billing.invoice", "billing.receipt.read"}, actions)
+	assert.False(t, everything)
+	actions, _ = evaluator.AllowedActions(auditor, owned)
+	assert.Equal(t, []string{"billing.*", "billing.invoice", "billing.invoice.void", "billing.receipt.read"}, actions)
+
+	// An explicit option takes precedence over the config.
+	evaluator, err = baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider, baccess.WithActionMatcher(baccess.StrictMatcher{}))
+	require.NoError(t, err)
+	assert.False(t, evaluator.Evaluate(request{Subject: accountant, Action: "billing.invoice.read"}))
+
+	cfg.Matcher = "tree"
+	_, err = baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), provider)
+	assert.EqualError(t, err, "unknown action matcher 'tree'")
+}
@@ -0,0 +1,359 @@
+package baccess
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ResidualOp is the kind of a Residual node.
+type ResidualOp string
+
+const (
+	ResidualTrue  ResidualOp = "true"
+	ResidualFalse ResidualOp = "false"
+	ResidualAnd   ResidualOp = "and"
+	ResidualOr    ResidualOp = "or"
+	ResidualNot   ResidualOp = "not"
+	// ResidualEquals holds when the resource field Field equals Value.
+	ResidualEquals ResidualOp = "eq"
+	// ResidualContains holds when the list-valued resource field Field
+	// contains Value.
+	ResidualContains ResidualOp = "contains"
+)
+
+// Residual is what remains of a policy once everything about the subject is
+// known: a condition over named resource fields, e.g.
+// owner_id = "u1" OR status = "published". It is the result of
+// PartialEvaluate and can be rendered as SQL with ToSQL.
+type Residual struct {
+	Op ResidualOp
+	// Field and Value are set for ResidualEquals and ResidualContains.
+	Field string
+	Value any
+	// Operands are set for ResidualAnd, ResidualOr and ResidualNot.
+	Operands []Residual
+}
+
+var (
+	residualTrue  = Residual{Op: ResidualTrue}
+	residualFalse = Residual{Op: ResidualFalse}
+)
+
+func residualBool(ok bool) Residual {
+	if ok {
+		return residualTrue
+	}
+
+	return residualFalse
+}
+
+// residualAnd conjoins a and b, folding constants and flattening nested
+// conjunctions.
+func residualAnd(a, b Residual) Residual {
+	switch {
+	case a.Op == ResidualFalse || b.Op == ResidualFalse:
+		return residualFalse
+	case a.Op == ResidualTrue:
+		return b
+	case b.Op == ResidualTrue:
+		return a
+	}
+
+	return Residual{Op: ResidualAnd, Operands: slices.Concat(a.flatten(ResidualAnd), b.flatten(ResidualAnd))}
+}
+
+// residualOr disjoins a and b, folding constants and flattening nested
+// disjunctions.
+func residualOr(a, b Residual) Residual {
+	switch {
+	case a.Op == ResidualTrue || b.Op == ResidualTrue:
+		return residualTrue
+	case a.Op == ResidualFalse:
+		return b
+	case b.Op == ResidualFalse:
+		return a
+	}
+
+	return Residual{Op: ResidualOr, Operands: slices.Concat(a.flatten(ResidualOr), b.flatten(ResidualOr))}
+}
+
+func residualNot(r Residual) Residual {
+	switch r.Op {
+	case ResidualTrue:
+		return residualFalse
+	case ResidualFalse:
+		return residualTrue
+	case ResidualNot:
+		return r.Operands[0]
+	}
+
+	return Residual{Op: ResidualNot, Operands: []Residual{r}}
+}
+
+func (r Residual) flatten(op ResidualOp) []Residual {
+	if r.Op == op {
+		return r.Operands
+	}
+
+	return []Residual{r}
+}
+
+func (r Residual) String() string {
+	switch r.Op {
+	case ResidualTrue, ResidualFalse:
+		return string(r.Op)
+	case ResidualEquals:
+		return fmt.Sprintf("%s = %#v", r.Field, r.Value)
+	case ResidualContains:
+		return fmt.Sprintf("%#v in %s", r.Value, r.Field)
+	case ResidualNot:
+		return "NOT (" + r.Operands[0].String() + ")"
+	}
+
+	operands := make([]string, len(r.Operands))
+	for i, operand := range r.Operands {
+		operands[i] = operand.String()
+		if operand.Op == ResidualOr || operand.Op == ResidualAnd {
+			operands[i] = "(" + operands[i] + ")"
+		}
+	}
+
+	return strings.Join(operands, " "+strings.ToUpper(string(r.Op))+" ")
+}
+
+// PartialPredicate is a predicate that can also be evaluated with only the
+// subject known. Partial receives a request whose Resource is the zero value
+// and returns the condition the resource must meet for Predicate to hold.
+type PartialPredicate[S any, R any] struct {
+	Predicate Predicate[AccessRequest[S, R]]
+	Partial   func(req AccessRequest[S, R]) Residual
+}
+
+func (p PartialPredicate[S, R]) IsSatisfiedBy(req AccessRequest[S, R]) bool {
+	return p.Predicate(req)
+}
+
+func (p PartialPredicate[S, R]) And(other PartialPredicate[S, R]) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: p.Predicate.And(other.Predicate),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return residualAnd(p.Partial(req), other.Partial(req))
+		},
+	}
+}
+
+func (p PartialPredicate[S, R]) Or(other PartialPredicate[S, R]) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: p.Predicate.Or(other.Predicate),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return residualOr(p.Partial(req), other.Partial(req))
+		},
+	}
+}
+
+func (p PartialPredicate[S, R]) Not() PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: p.Predicate.Not(),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return residualNot(p.Partial(req))
+		},
+	}
+}
+
+// PartialResourceMatches is ResourceMatches for the resource field named
+// field.
+func PartialResourceMatches[S any, R any, T comparable](
+	field string,
+	extractor func(R) T,
+	target T,
+) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: ResourceMatches[S](extractor, target),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return Residual{Op: ResidualEquals, Field: field, Value: target}
+		},
+	}
+}
+
+// PartialFieldEquals is FieldEquals for the resource field named field.
+func PartialFieldEquals[S any, R any, T comparable](
+	field string,
+	subjVal func(S) T,
+	resVal func(R) T,
+) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: FieldEquals(subjVal, resVal),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return Residual{Op: ResidualEquals, Field: field, Value: subjVal(req.Subject)}
+		},
+	}
+}
+
+// PartialSubjectInResourceList is SubjectInResourceList for the list-valued
+// resource field named field.
+func PartialSubjectInResourceList[S any, R any, T comparable](
+	field string,
+	subjVal func(S) T,
+	resList func(R) []T,
+) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: SubjectInResourceList(subjVal, resList),
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return Residual{Op: ResidualContains, Field: field, Value: subjVal(req.Subject)}
+		},
+	}
+}
+
+// PartialSubject wraps a predicate that never inspects the resource, such as
+// SubjectAttrEquals, so that it can take part in partial evaluation.
+func PartialSubject[S any, R any](p Predicate[AccessRequest[S, R]]) PartialPredicate[S, R] {
+	return PartialPredicate[S, R]{
+		Predicate: p,
+		Partial: func(req AccessRequest[S, R]) Residual {
+			return residualBool(p(req))
+		},
+	}
+}
+
+// PartialProvider is implemented by providers that can return the partial
+// form of a named predicate, which BuildEvaluator keeps for PartialEvaluate.
+type PartialProvider[S any, R any] interface {
+	GetPartial(name string) (PartialPredicate[S, R], error)
+}
+
+// AddPartialPolicy registers a policy that allows the action when p is
+// satisfied and that PartialEvaluate can reduce to a Residual.
+func (e *Evaluator[S, R]) AddPartialPolicy(action string, p PartialPredicate[S, R]) {
+	e.add(policy[S, R]{key: action, effect: EffectAllow, pred: p.Predicate, partial: p.Partial})
+}
+
+// AddPartialDenyPolicy is the deny counterpart of AddPartialPolicy.
+func (e *Evaluator[S, R]) AddPartialDenyPolicy(action string, p PartialPredicate[S, R]) {
+	e.add(policy[S, R]{key: action, effect: EffectDeny, pred: p.Predicate, partial: p.Partial})
+}
+
+// PartialEvaluate resolves every part of the policies for action that
+// depends only on subject and returns the condition a resource must meet for
+// Evaluate to allow the request, so that the decision can be pushed down into
+// a database query. Environment is not set on the requests it evaluates.
+//
+// Every policy that can still apply to the subject must have a partial form:
+// policies added with AddPartialPolicy/AddPartialDenyPolicy, and Config rules
+// whose conditions are "*" or only reference predicates the provider returns
+// from GetPartial. Otherwise an error names the first policy that has none.
+func (e *Evaluator[S, R]) PartialEvaluate(subject S, action string) (Residual, error) {
+	plan := e.plan(subject, action, nil)
+	req := AccessRequest[S, R]{Subject: subject, Action: action}
+
+	residual := func(p *filterPolicy[S, R]) (Residual, error) {
+		if p.partial == nil {
+			if p.condition != "" {
+				return Residual{}, fmt.Errorf("policy %q: condition '%s' cannot be partially evaluated", p.key, p.condition)
+			}
+			return Residual{}, fmt.Errorf("policy %q cannot be partially evaluated", p.key)
+		}
+		return p.partial(req), nil
+	}
+
+	switch plan.algorithm {
+	case PermitOverrides:
+		allow := residualFalse
+		for i := range plan.policies {
+			p := &plan.policies[i]
+			if p.effect != EffectAllow {
+				continue
+			}
+			r, err := residual(p)
+			if err != nil {
+				return Residual{}, err
+			}
+			if allow = residualOr(allow, r); allow.Op == ResidualTrue {
+				break
+			}
+		}
+		return allow, nil
+
+	case FirstApplicable:
+		// Policies after one that always applies are never reached.
+		var residuals []Residual
+		for i := range plan.policies {
+			r, err := residual(&plan.policies[i])
+			if err != nil {
+				return Residual{}, err
+			}
+			residuals = append(residuals, r)
+			if r.Op == ResidualTrue {
+				break
+			}
+		}
+		// A policy decides when it applies and no earlier one did, so fold
+		// from the last policy back to the first.
+		decision := residualFalse
+		for i := len(residuals) - 1; i >= 0; i-- {
+			if plan.policies[i].effect == EffectAllow {
+				decision = residualOr(residuals[i], decision)
+			} else {
+				decision = residualAnd(residualNot(residuals[i]), decision)
+			}
+		}
+		return decision, nil
+
+	default:
+		allow, deny := residualFalse, residualFalse
+		for i := range plan.policies {
+			p := &plan.policies[i]
+			r, err := residual(p)
+			if err != nil {
+				return Residual{}, err
+			}
+			if p.effect == EffectDeny {
+				deny = residualOr(deny, r)
+			} else {
+				allow = residualOr(allow, r)
+			}
+		}
+		return residualAnd(allow, residualNot(deny)), nil
+	}
+}
+
+// compilePartialCondition composes the partial forms of expr's predicates,
+// resolved through lookup. It returns nil if any of them has none.
+func compilePartialCondition[S any, R any](
+	expr conditionExpr,
+	lookup func(leaf conditionRef) func(AccessRequest[S, R]) Residual,
+) func(AccessRequest[S, R]) Residual {
+	if ref, ok := expr.(conditionRef); ok {
+		return lookup(ref)
+	}
+
+	var left, right func(AccessRequest[S, R]) Residual
+	switch e := expr.(type) {
+	case conditionNot:
+		left = compilePartialCondition(e.operand, lookup)
+	case conditionAnd:
+		left, right = compilePartialCondition(e.left, lookup), compilePartialCondition(e.right, lookup)
+	case conditionOr:
+		left, right = compilePartialCondition(e.left, lookup), compilePartialCondition(e.right, lookup)
+	default:
+		panic(fmt.Sprintf("baccess: unexpected condition node %T", expr))
+	}
+	if left == nil {
+		return nil
+	}
+
+	switch expr.(type) {
+	case conditionNot:
+		return func(req AccessRequest[S, R]) Residual { return residualNot(left(req)) }
+	case conditionAnd:
+		if right == nil {
+			return nil
+		}
+		return func(req AccessRequest[S, R]) Residual { return residualAnd(left(req), right(req)) }
+	default:
+		if right == nil {
+			return nil
+		}
+		return func(req AccessRequest[S, R]) Residual { return residualOr(left(req), right(req)) }
+	}
+}
@@ -0,0 +1,296 @@
+package baccess_test
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type partialRequest = baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+
+func partialRegistry() *baccess.Registry[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+	registry := baccess.NewRegistry[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	registry.RegisterPartial("isOwner", baccess.PartialFieldEquals[auth_test_utils.MockSubject](
+		"owner",
+		func(s auth_test_utils.MockSubject) string { return s.ID },
+		func(r auth_test_utils.MockResource) string { return r.OwnerID },
+	))
+	registry.RegisterPartial("isDraft", baccess.PartialResourceMatches[auth_test_utils.MockSubject](
+		"status",
+		func(r auth_test_utils.MockResource) string { return r.Status },
+		"draft",
+	))
+	registry.RegisterPartial("isCollaborator", baccess.PartialSubjectInResourceList[auth_test_utils.MockSubject](
+		"collaborators",
+		func(s auth_test_utils.MockSubject) string { return s.ID },
+		func(r auth_test_utils.MockResource) []string { return r.Collaborators },
+	))
+	registry.RegisterPartial("isActive", baccess.PartialSubject(func(req partialRequest) bool {
+		return req.Subject.IsActive
+	}))
+	registry.Register("isPublished", func(req partialRequest) bool {
+		return req.Resource.Status == "published"
+	})
+
+	return registry
+}
+
+// matchResidual evaluates r against a resource, standing in for a database.
+func matchResidual(t *testing.T, r baccess.Residual, resource auth_test_utils.MockResource) bool {
+	field := func(name string) any {
+		switch name {
+		case "owner":
+			return resource.OwnerID
+		case "status":
+			return resource.Status
+		case "collaborators":
+			return resource.Collaborators
+		}
+		t.Fatalf("unexpected field %q", name)
+		return nil
+	}
+
+	switch r.Op {
+	case baccess.ResidualTrue:
+		return true
+	case baccess.ResidualFalse:
+		return false
+	case baccess.ResidualEquals:
+		return field(r.Field) == r.Value
+	case baccess.ResidualContains:
+		return slices.Contains(field(r.Field).([]string), r.Value.(string))
+	case baccess.ResidualNot:
+		return !matchResidual(t, r.Operands[0], resource)
+	case baccess.ResidualAnd:
+		for _, operand := range r.Operands {
+			if !matchResidual(t, operand, resource) {
+				return false
+			}
+		}
+		return true
+	case baccess.ResidualOr:
+		for _, operand := range r.Operands {
+			if matchResidual(t, operand, resource) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Fatalf("unexpected residual %q", r.Op)
+	return false
+}
+
+func TestEvaluator_PartialEvaluate(t *testing.T) {
+	var resources []auth_test_utils.MockResource
+	for i := range 24 {
+		resources = append(resources, auth_test_utils.MockResource{
+			ID:            fmt.Sprint(i),
+			OwnerID:       []string{"u1", "u2"}[i%2],
+			Status:        []string{"draft", "published", "archived"}[i%3],
+			Collaborators: [][]string{nil, {"u1"}, {"u2", "u3"}, {"u1", "u3"}}[i%4],
+		})
+	}
+	subjects := []auth_test_utils.MockSubject{
+		{ID: "u1", Roles: []string{"editor"}, IsActive: true},
+		{ID: "u1", Roles: []string{"editor", "contractor"}},
+		{ID: "u3", Roles: []string{"viewer"}, IsActive: true},
+		{ID: "u2", Roles: []string{"admin", "contractor"}},
+		{ID: "u4"},
+	}
+
+	for _, algorithm := range []baccess.CombiningAlgorithm{baccess.DenyOverrides, baccess.PermitOverrides, baccess.FirstApplicable} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			cfg := &baccess.Config{
+				Algorithm: algorithm,
+				Policies: map[string]baccess.RolePolicyConfig{
+					"admin":      {Allow: []string{"*"}},
+					"editor":     {Allow: []string{"read", "edit:isOwner|isCollaborator&!isDraft"}},
+					"contractor": {Deny: []string{"edit:isDraft|!isActive"}},
+					"viewer":     {Allow: []string{"read:isActive&isCollaborator"}},
+				},
+			}
+			evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), partialRegistry())
+			require.NoError(t, err)
+			evaluator.AddPartialDenyPolicy("read", baccess.PartialResourceMatches[auth_test_utils.MockSubject](
+				"status",
+				func(r auth_test_utils.MockResource) string { return r.Status },
+				"archived",
+			))
+
+			for _, subject := range subjects {
+				for _, action := range []string{"read", "edit", "delete"} {
+					residual, err := evaluator.PartialEvaluate(subject, action)
+					require.NoError(t, err)
+
+					for _, resource := range resources {
+						want := evaluator.Evaluate(partialRequest{Subject: subject, Resource: resource, Action: action})
+						assert.Equal(t, want, matchResidual(t, residual, resource), "%v %s %v: %s", subject.Roles, action, resource, residual)
+					}
+				}
+			}
+		})
+	}
+
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor":     {Allow: []string{"edit:isOwner|isCollaborator&!isDraft"}},
+			"contractor": {Deny: []string{"edit:isDraft|!isActive"}},
+			"publisher":  {Allow: []string{"publish:isPublished"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), partialRegistry())
+	require.NoError(t, err)
+
+	residual, err := evaluator.PartialEvaluate(auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor", "contractor"}, IsActive: true}, "edit")
+	require.NoError(t, err)
+	assert.Equal(t, `(owner = "u1" OR ("u1" in collaborators AND NOT (status = "draft"))) AND NOT (status = "draft")`, residual.String())
+
+	// Subject-only predicates are resolved up front.
+	residual, err = evaluator.PartialEvaluate(auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor", "contractor"}}, "edit")
+	require.NoError(t, err)
+	assert.Equal(t, baccess.ResidualFalse, residual.Op)
+
+	// Policies that cannot apply to the subject need no partial form.
+	residual, err = evaluator.PartialEvaluate(auth_test_utils.MockSubject{ID: "u1"}, "publish")
+	require.NoError(t, err)
+	assert.Equal(t, baccess.ResidualFalse, residual.Op)
+
+	_, err = evaluator.PartialEvaluate(auth_test_utils.MockSubject{ID: "u1", Roles: []string{"publisher"}}, "publish")
+	assert.EqualError(t, err, `policy "publish:isPublished": condition 'isPublished' cannot be partially evaluated`)
+
+	evaluator.AddPolicy("edit", alwaysTrue[auth_test_utils.MockSubject, auth_test_utils.MockResource]())
+	_, err = evaluator.PartialEvaluate(auth_test_utils.MockSubject{ID: "u1"}, "edit")
+	assert.EqualError(t, err, `policy "edit" cannot be partially evaluated`)
+}
+
+func TestResidual_ToSQL(t *testing.T) {
+	columns := map[string]string{
+		"owner":         "owner_id",
+		"status":        "status",
+		"collaborators": "collaborator_ids",
+		"team":          "id IN (SELECT doc_id FROM doc_teams WHERE team_id = ?)",
+	}
+	eq := func(field string, value any) baccess.Residual {
+		return baccess.Residual{Op: baccess.ResidualEquals, Field: field, Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		residual baccess.Residual
+		opts     []baccess.SQLOption
+		want     string
+		wantArgs []any
+	}{
+		{"true", baccess.Residual{Op: baccess.ResidualTrue}, nil, "1 = 1", nil},
+		{"false", baccess.Residual{Op: baccess.ResidualFalse}, nil, "1 = 0", nil},
+		{"equals", eq("owner", "u1"), nil, "owner_id = ?", []any{"u1"}},
+		{"null", eq("owner", nil), nil, "owner_id IS NULL", nil},
+		{
+			"contains",
+			baccess.Residual{Op: baccess.ResidualContains, Field: "collaborators", Value: "u1"},
+			nil,
+			"? = ANY(collaborator_ids)",
+			[]any{"u1"},
+		},
+		{
+			"contains template",
+			baccess.Residual{Op: baccess.ResidualContains, Field: "team", Value: 7},
+			nil,
+			"id IN (SELECT doc_id FROM doc_teams WHERE team_id = ?)",
+			[]any{7},
+		},
+		{
+			"nested",
+			baccess.Residual{Op: baccess.ResidualAnd, Operands: []baccess.Residual{
+				{Op: baccess.ResidualOr, Operands: []baccess.Residual{
+					eq("owner", "u1"),
+					{Op: baccess.ResidualContains, Field: "collaborators", Value: "u1"},
+				}},
+				{Op: baccess.ResidualNot, Operands: []baccess.Residual{eq("status", "archived")}},
+			}},
+			[]baccess.SQLOption{baccess.WithPlaceholder(baccess.DollarPlaceholder)},
+			"(owner_id = $1 OR $2 = ANY(collaborator_ids)) AND NOT COALESCE((status = $3), 1 = 0)",
+			[]any{"u1", "u1", "archived"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := tt.residual.ToSQL(columns, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, where)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+
+	_, _, err := eq("title", "x").ToSQL(columns)
+	assert.EqualError(t, err, "no column mapped for field 'title'")
+}
+
+// TestResidual_ToSQL_Null runs the rendered clause against SQLite, where an
+// empty field is stored as NULL, and compares the rows with Evaluate.
+func TestResidual_ToSQL_Null(t *testing.T) {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor": {Allow: []string{"edit:isOwner"}, Deny: []string{"edit:isDraft"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), partialRegistry())
+	require.NoError(t, err)
+
+	subject := auth_test_utils.MockSubject{ID: "u1", Roles: []string{"editor"}}
+	resources := []auth_test_utils.MockResource{
+		{ID: "1", OwnerID: "u1", Status: "draft"},
+		{ID: "2", OwnerID: "u1", Status: "published"},
+		{ID: "3", OwnerID: "u1"},
+		{ID: "4", Status: "published"},
+		{ID: "5"},
+	}
+
+	residual, err := evaluator.PartialEvaluate(subject, "edit")
+	require.NoError(t, err)
+	where, args, err := residual.ToSQL(map[string]string{"owner": "owner_id", "status": "status"})
+	require.NoError(t, err)
+
+	literal := func(value string) string {
+		if value == "" {
+			return "NULL"
+		}
+		return "'" + value + "'"
+	}
+	for _, arg := range args {
+		where = strings.Replace(where, "?", literal(arg.(string)), 1)
+	}
+
+	var script strings.Builder
+	script.WriteString("CREATE TABLE docs (id TEXT, owner_id TEXT, status TEXT);\n")
+	var want []string
+	for _, resource := range resources {
+		fmt.Fprintf(&script, "INSERT INTO docs VALUES (%s, %s, %s);\n", literal(resource.ID), literal(resource.OwnerID), literal(resource.Status))
+		if evaluator.Evaluate(partialRequest{Subject: subject, Resource: resource, Action: "edit"}) {
+			want = append(want, resource.ID)
+		}
+	}
+	fmt.Fprintf(&script, "SELECT id FROM docs WHERE %s ORDER BY id;\n", where)
+
+	cmd := exec.Command(sqlite, ":memory:")
+	cmd.Stdin = strings.NewReader(script.String())
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s", out)
+
+	assert.Equal(t, []string{"2", "3"}, want)
+	assert.Equal(t, want, strings.Fields(string(out)), where)
+}
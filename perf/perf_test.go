@@ -1,6 +1,7 @@
 package perf
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/brian-nunez/baccess"
@@ -224,3 +225,147 @@ func BenchmarkPolicyEvaluation(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkPolicyScaling(b *testing.B) {
+	registry := baccess.NewRegistry[MockUser, MockDocument]()
+	registry.Register("isOwner", baccess.FieldEquals(
+		func(u MockUser) string { return u.ID },
+		func(d MockDocument) string { return d.OwnerID },
+	))
+
+	editorUser := MockUser{ID: "editor1", Roles: []string{"editor"}, Department: "Engineering"}
+	ownedDoc := MockDocument{OwnerID: "editor1", Status: "draft"}
+
+	for _, size := range []int{10, 100, 10000} {
+		// Half of the rules are plain actions, half are owner-conditional.
+		allow := make([]string, 0, size)
+		for i := 0; i < size/2; i++ {
+			allow = append(allow, fmt.Sprintf("action%d", i), fmt.Sprintf("action%d:isOwner", i))
+		}
+		cfg := &baccess.Config{
+			Policies: map[string]baccess.RolePolicyConfig{
+				"admin":  {Allow: []string{"*"}},
+				"editor": {Allow: allow},
+			},
+		}
+
+		evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[MockUser, MockDocument](), registry)
+		if err != nil {
+			b.Fatalf("Failed to build evaluator: %v", err)
+		}
+		evaluator.Compile()
+
+		last := fmt.Sprintf("action%d", size/2-1)
+
+		b.Run(fmt.Sprintf("Policies_%d/PlainAction", size), func(b *testing.B) {
+			req := baccess.AccessRequest[MockUser, MockDocument]{Subject: editorUser, Resource: ownedDoc, Action: last}
+			b.ReportAllocs()
+			for b.Loop() {
+				evaluator.Evaluate(req)
+			}
+		})
+
+		b.Run(fmt.Sprintf("Policies_%d/ConditionalAction", size), func(b *testing.B) {
+			req := baccess.AccessRequest[MockUser, MockDocument]{Subject: editorUser, Resource: ownedDoc, Action: last + ":isOwner"}
+			b.ReportAllocs()
+			for b.Loop() {
+				evaluator.Evaluate(req)
+			}
+		})
+
+		b.Run(fmt.Sprintf("Policies_%d/UnknownAction", size), func(b *testing.B) {
+			req := baccess.AccessRequest[MockUser, MockDocument]{Subject: editorUser, Resource: ownedDoc, Action: "unknown"}
+			b.ReportAllocs()
+			for b.Loop() {
+				evaluator.Evaluate(req)
+			}
+		})
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	registry := baccess.NewRegistry[MockUser, MockDocument]()
+	registry.Register("isOwner", baccess.FieldEquals(
+		func(u MockUser) string { return u.ID },
+		func(d MockDocument) string { return d.OwnerID },
+	))
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"admin":  {Allow: []string{"*"}},
+			"editor": {Allow: []string{"read:isOwner", "update:isOwner"}},
+			"viewer": {Allow: []string{"list"}},
+		},
+	}
+	evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[MockUser, MockDocument](), registry)
+	if err != nil {
+		b.Fatalf("Failed to build evaluator: %v", err)
+	}
+	evaluator.Compile()
+
+	editorUser := MockUser{ID: "editor1", Roles: []string{"editor"}}
+	docs := make([]MockDocument, 500)
+	for i := range docs {
+		docs[i] = MockDocument{OwnerID: fmt.Sprintf("editor%d", i%4), Status: "draft"}
+	}
+
+	b.Run("EvaluateLoop", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var allowed []MockDocument
+			for _, doc := range docs {
+				req := baccess.AccessRequest[MockUser, MockDocument]{Subject: editorUser, Resource: doc, Action: "read"}
+				if evaluator.Evaluate(req) {
+					allowed = append(allowed, doc)
+				}
+			}
+		}
+	})
+
+	b.Run("Filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			evaluator.Filter(editorUser, "read", docs)
+		}
+	})
+}
+
+func BenchmarkActionMatcher(b *testing.B) {
+	registry := baccess.NewRegistry[MockUser, MockDocument]()
+	registry.Register("isOwner", baccess.FieldEquals(
+		func(u MockUser) string { return u.ID },
+		func(d MockDocument) string { return d.OwnerID },
+	))
+	cfg := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"admin":  {Allow: []string{"*"}},
+			"editor": {Allow: []string{"documents.read", "documents.update:isOwner", "documents.*.read", "documents.**.comment:isOwner"}},
+		},
+	}
+
+	editorUser := MockUser{ID: "editor1", Roles: []string{"editor"}}
+	ownedDoc := MockDocument{OwnerID: "editor1", Status: "draft"}
+
+	matchers := []struct {
+		name    string
+		matcher baccess.ActionMatcher
+	}{
+		{"Default", baccess.DefaultMatcher{}},
+		{"Strict", baccess.StrictMatcher{}},
+		{"Glob", baccess.GlobMatcher{}},
+	}
+	for _, m := range matchers {
+		evaluator, err := baccess.BuildEvaluator(cfg, baccess.NewRBAC[MockUser, MockDocument](), registry, baccess.WithActionMatcher(m.matcher))
+		if err != nil {
+			b.Fatalf("Failed to build evaluator: %v", err)
+		}
+		evaluator.Compile()
+
+		b.Run(m.name, func(b *testing.B) {
+			req := baccess.AccessRequest[MockUser, MockDocument]{Subject: editorUser, Resource: ownedDoc, Action: "documents.update"}
+			b.ReportAllocs()
+			for b.Loop() {
+				evaluator.Evaluate(req)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package baccess
 
 import (
+	"fmt"
 	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 func HasRole[S RoleBearer, R any](role string) Predicate[AccessRequest[S, R]] {
@@ -26,30 +31,199 @@ func HasAnyRole[S RoleBearer, R any](targetRoles ...string) Predicate[AccessRequ
 	}
 }
 
+// RBAC is safe for concurrent use: predicates read an immutable snapshot of
+// the inheritance graph, which Inherit replaces atomically.
 type RBAC[S RoleBearer, R any] struct {
+	mu        sync.Mutex
+	hierarchy atomic.Pointer[roleHierarchy]
+}
+
+type roleHierarchy struct {
+	// inherits maps a role to the roles it directly inherits from.
+	inherits map[string][]string
+	// implied maps a role to every role it transitively inherits from.
+	implied map[string]map[string]struct{}
 }
 
 func NewRBAC[S RoleBearer, R any]() *RBAC[S, R] {
 	return &RBAC[S, R]{}
 }
 
-// HasRole creates a predicate that checks if the subject has the role.
+// Inherit declares that role inherits every permission of the inherited roles
+// (e.g. Inherit("admin", "editor") makes every admin an editor as well).
+func (rbac *RBAC[S, R]) Inherit(role string, inherited ...string) {
+	rbac.mu.Lock()
+	defer rbac.mu.Unlock()
+
+	inherits := make(map[string][]string)
+	if h := rbac.hierarchy.Load(); h != nil {
+		for r, parents := range h.inherits {
+			inherits[r] = slices.Clone(parents)
+		}
+	}
+
+	for _, parent := range inherited {
+		if !slices.Contains(inherits[role], parent) {
+			inherits[role] = append(inherits[role], parent)
+		}
+	}
+
+	rbac.hierarchy.Store(resolveHierarchy(inherits))
+}
+
+// Implies reports whether a subject holding role also holds target, either
+// directly or through inheritance.
+func (rbac *RBAC[S, R]) Implies(role, target string) bool {
+	if role == target {
+		return true
+	}
+	h := rbac.hierarchy.Load()
+	if h == nil {
+		return false
+	}
+	_, ok := h.implied[role][target]
+
+	return ok
+}
+
+// EffectiveRoles expands roles with every role they transitively inherit.
+func (rbac *RBAC[S, R]) EffectiveRoles(roles []string) []string {
+	effective := slices.Clone(roles)
+	h := rbac.hierarchy.Load()
+	if h == nil {
+		return effective
+	}
+
+	for _, role := range roles {
+		for inherited := range h.implied[role] {
+			if !slices.Contains(effective, inherited) {
+				effective = append(effective, inherited)
+			}
+		}
+	}
+
+	return effective
+}
+
+// Validate reports inheritance cycles such as "admin -> editor -> admin".
+func (rbac *RBAC[S, R]) Validate() error {
+	h := rbac.hierarchy.Load()
+	if h == nil {
+		return nil
+	}
+
+	return findInheritanceCycle(h.inherits)
+}
+
+// findInheritanceCycle reports the first inheritance cycle found, visiting
+// roles in name order so the reported cycle is deterministic.
+func findInheritanceCycle(inherits map[string][]string) error {
+	roles := make([]string, 0, len(inherits))
+	for role := range inherits {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(role string) error
+	visit = func(role string) error {
+		switch state[role] {
+		case visiting:
+			start := slices.Index(path, role)
+			cycle := append(slices.Clone(path[start:]), role)
+			return fmt.Errorf("role inheritance cycle: %s", strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+
+		state[role] = visiting
+		path = append(path, role)
+		for _, parent := range inherits[role] {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[role] = done
+
+		return nil
+	}
+
+	for _, role := range roles {
+		if err := visit(role); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveHierarchy computes the transitive closure of the inheritance graph.
+func resolveHierarchy(inherits map[string][]string) *roleHierarchy {
+	implied := make(map[string]map[string]struct{}, len(inherits))
+
+	for role := range inherits {
+		reachable := make(map[string]struct{})
+		stack := slices.Clone(inherits[role])
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if _, seen := reachable[current]; seen {
+				continue
+			}
+			reachable[current] = struct{}{}
+			stack = append(stack, inherits[current]...)
+		}
+		implied[role] = reachable
+	}
+
+	return &roleHierarchy{inherits: inherits, implied: implied}
+}
+
+// clone returns an independent copy so BuildEvaluator can layer config
+// inheritance on top without mutating the caller's RBAC.
+func (rbac *RBAC[S, R]) clone() *RBAC[S, R] {
+	c := NewRBAC[S, R]()
+	// The snapshot is never mutated in place, so it can be shared.
+	c.hierarchy.Store(rbac.hierarchy.Load())
+
+	return c
+}
+
+// HasRole creates a predicate that checks if the subject has the role,
+// either directly or through an inheriting role.
 func (rbac *RBAC[S, R]) HasRole(targetRole string) Predicate[AccessRequest[S, R]] {
 	return func(req AccessRequest[S, R]) bool {
 		userRoles := req.Subject.GetRoles()
 
-		return slices.Contains(userRoles, targetRole)
+		for _, ur := range userRoles {
+			if rbac.Implies(ur, targetRole) {
+				return true
+			}
+		}
+
+		return false
 	}
 }
 
-// HasAnyRole creates a predicate that checks if the subject has any of the target roles.
+// HasAnyRole creates a predicate that checks if the subject has any of the target roles,
+// either directly or through an inheriting role.
 func (rbac *RBAC[S, R]) HasAnyRole(targetRoles ...string) Predicate[AccessRequest[S, R]] {
 	return func(req AccessRequest[S, R]) bool {
 		userRoles := req.Subject.GetRoles()
 
 		for _, ur := range userRoles {
-			if slices.Contains(targetRoles, ur) {
-				return true
+			for _, target := range targetRoles {
+				if rbac.Implies(ur, target) {
+					return true
+				}
 			}
 		}
 
@@ -173,3 +173,44 @@ func TestRBAC_HasAnyRole(t *testing.T) {
 	predicate = rbac.HasAnyRole("user")
 	assert.True(t, predicate.IsSatisfiedBy(req))
 }
+
+func TestRBAC_Inherit(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]()
+	rbac.Inherit("admin", "editor")
+	rbac.Inherit("editor", "viewer")
+
+	adminReq := baccess.AccessRequest[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockRoleBearer{Roles: []string{"admin"}},
+	}
+	viewerReq := baccess.AccessRequest[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockRoleBearer{Roles: []string{"viewer"}},
+	}
+
+	assert.True(t, rbac.HasRole("admin").IsSatisfiedBy(adminReq))
+	assert.True(t, rbac.HasRole("editor").IsSatisfiedBy(adminReq))
+	assert.True(t, rbac.HasRole("viewer").IsSatisfiedBy(adminReq))
+	assert.False(t, rbac.HasRole("editor").IsSatisfiedBy(viewerReq))
+
+	assert.True(t, rbac.HasAnyRole("guest", "viewer").IsSatisfiedBy(adminReq))
+	assert.False(t, rbac.HasAnyRole("guest", "admin").IsSatisfiedBy(viewerReq))
+
+	assert.ElementsMatch(t, []string{"admin", "editor", "viewer"}, rbac.EffectiveRoles([]string{"admin"}))
+	assert.NoError(t, rbac.Validate())
+}
+
+func TestRBAC_ValidateCycle(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]()
+	rbac.Inherit("admin", "editor")
+	rbac.Inherit("editor", "viewer")
+	rbac.Inherit("viewer", "admin")
+
+	err := rbac.Validate()
+	assert.Error(t, err)
+	assert.ErrorContains(t, err, "role inheritance cycle: admin -> editor -> viewer -> admin")
+
+	// Resolution still terminates when the graph has a cycle.
+	req := baccess.AccessRequest[auth_test_utils.MockRoleBearer, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockRoleBearer{Roles: []string{"viewer"}},
+	}
+	assert.True(t, rbac.HasRole("editor").IsSatisfiedBy(req))
+}
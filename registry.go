@@ -2,25 +2,95 @@ package baccess
 
 import (
 	"fmt"
+	"sync"
 )
 
+// PredicateFactory builds a predicate from the arguments given in a rule
+// condition, e.g. ["department", "finance"] for attrEquals(department,finance).
+// It should reject arguments of the wrong number or type with an error.
+type PredicateFactory[S any, R any] func(args []string) (Predicate[AccessRequest[S, R]], error)
+
+// Registry is safe for concurrent use.
 type Registry[S any, R any] struct {
-	preds map[string]Predicate[AccessRequest[S, R]]
+	mu        sync.RWMutex
+	preds     map[string]Predicate[AccessRequest[S, R]]
+	factories map[string]PredicateFactory[S, R]
+	partials  map[string]PartialPredicate[S, R]
 }
 
 func NewRegistry[S any, R any]() *Registry[S, R] {
 	return &Registry[S, R]{
-		preds: make(map[string]Predicate[AccessRequest[S, R]]),
+		preds:     make(map[string]Predicate[AccessRequest[S, R]]),
+		factories: make(map[string]PredicateFactory[S, R]),
+		partials:  make(map[string]PartialPredicate[S, R]),
 	}
 }
 
 func (r *Registry[S, R]) Register(name string, p Predicate[AccessRequest[S, R]]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.preds[name] = p
+	delete(r.partials, name)
+}
+
+// RegisterFactory registers a factory for parameterized conditions such as
+// "levelAtLeast(3)".
+func (r *Registry[S, R]) RegisterFactory(name string, factory PredicateFactory[S, R]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.factories[name] = factory
+}
+
+// RegisterPartial registers p under name like Register, and keeps its partial
+// form so that evaluators built from a Config can use PartialEvaluate.
+func (r *Registry[S, R]) RegisterPartial(name string, p PartialPredicate[S, R]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.preds[name] = p.Predicate
+	r.partials[name] = p
 }
 
 func (r *Registry[S, R]) GetPredicate(name string) (Predicate[AccessRequest[S, R]], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	if p, ok := r.preds[name]; ok {
 		return p, nil
 	}
 	return nil, fmt.Errorf("predicate not found: %s", name)
 }
+
+// GetPartial returns the predicate registered under name with RegisterPartial.
+func (r *Registry[S, R]) GetPartial(name string) (PartialPredicate[S, R], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.partials[name]; ok {
+		return p, nil
+	}
+	return PartialPredicate[S, R]{}, fmt.Errorf("partial predicate not found: %s", name)
+}
+
+// BuildPredicate calls the factory registered under name with args.
+func (r *Registry[S, R]) BuildPredicate(name string, args []string) (Predicate[AccessRequest[S, R]], error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("predicate factory not found: %s", name)
+	}
+	return factory(args)
+}
+
+// ExpectArgs reports an error unless exactly n arguments were given. It is a
+// convenience for PredicateFactory implementations.
+func ExpectArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %d argument(s), got %d", n, len(args))
+	}
+	return nil
+}
@@ -74,3 +74,30 @@ func TestGetPredicate(t *testing.T) {
 	assert.Nil(t, p)
 	assert.EqualError(t, err, "predicate not found: nonExistentPredicate")
 }
+
+func TestRegisterFactory(t *testing.T) {
+	reg := baccess.NewRegistry[RegistryTestSubject, RegistryTestResource]()
+	reg.RegisterFactory("idIs", func(args []string) (baccess.Predicate[baccess.AccessRequest[RegistryTestSubject, RegistryTestResource]], error) {
+		if err := baccess.ExpectArgs(args, 1); err != nil {
+			return nil, err
+		}
+		return func(req baccess.AccessRequest[RegistryTestSubject, RegistryTestResource]) bool {
+			return req.Subject.ID == args[0]
+		}, nil
+	})
+
+	p, err := reg.BuildPredicate("idIs", []string{"u1"})
+	assert.NoError(t, err)
+	assert.True(t, p.IsSatisfiedBy(baccess.AccessRequest[RegistryTestSubject, RegistryTestResource]{Subject: RegistryTestSubject{ID: "u1"}}))
+	assert.False(t, p.IsSatisfiedBy(baccess.AccessRequest[RegistryTestSubject, RegistryTestResource]{Subject: RegistryTestSubject{ID: "u2"}}))
+
+	_, err = reg.BuildPredicate("idIs", nil)
+	assert.EqualError(t, err, "expected 1 argument(s), got 0")
+
+	_, err = reg.BuildPredicate("missing", nil)
+	assert.EqualError(t, err, "predicate factory not found: missing")
+
+	// Factories and plain predicates live in separate namespaces.
+	_, err = reg.GetPredicate("idIs")
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package baccess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type SQLOption func(*sqlOptions)
+
+type sqlOptions struct {
+	placeholder func(n int) string
+}
+
+// WithPlaceholder sets how the n-th (1-based) query parameter is written. The
+// default is "?"; use DollarPlaceholder for PostgreSQL.
+func WithPlaceholder(placeholder func(n int) string) SQLOption {
+	return func(o *sqlOptions) {
+		o.placeholder = placeholder
+	}
+}
+
+// DollarPlaceholder writes the n-th query parameter as $n.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// ToSQL renders r as the body of a parameterized WHERE clause, returning the
+// clause and its arguments in order. columns maps each resource field to the
+// SQL column it is stored in; columns are written verbatim and must not come
+// from user input.
+//
+// Equality is rendered as "col = ?", or "col IS NULL" for a nil value. A
+// ResidualContains column is rendered as "? = ANY(col)", which suits
+// PostgreSQL arrays; a mapping that contains "?" is used as a template
+// instead, e.g. "id IN (SELECT doc_id FROM collaborators WHERE user_id = ?)".
+// Constants are rendered as "1 = 1" and "1 = 0".
+//
+// Negation is rendered as "NOT COALESCE((...), 1 = 0)", so that a comparison
+// with a NULL column counts as false, as it does in Evaluate, rather than
+// making the whole negation unknown and dropping the row.
+func (r Residual) ToSQL(columns map[string]string, opts ...SQLOption) (string, []any, error) {
+	options := sqlOptions{placeholder: func(int) string { return "?" }}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	w := &sqlWriter{columns: columns, placeholder: options.placeholder}
+
+	if err := w.write(r); err != nil {
+		return "", nil, err
+	}
+
+	return w.sb.String(), w.args, nil
+}
+
+type sqlWriter struct {
+	columns     map[string]string
+	placeholder func(n int) string
+	sb          strings.Builder
+	args        []any
+}
+
+func (w *sqlWriter) param(value any) string {
+	w.args = append(w.args, value)
+	return w.placeholder(len(w.args))
+}
+
+func (w *sqlWriter) column(field string) (string, error) {
+	column, ok := w.columns[field]
+	if !ok {
+		return "", fmt.Errorf("no column mapped for field '%s'", field)
+	}
+
+	return column, nil
+}
+
+func (w *sqlWriter) write(r Residual) error {
+	switch r.Op {
+	case ResidualTrue:
+		w.sb.WriteString("1 = 1")
+	case ResidualFalse:
+		w.sb.WriteString("1 = 0")
+	case ResidualEquals:
+		column, err := w.column(r.Field)
+		if err != nil {
+			return err
+		}
+		if r.Value == nil {
+			w.sb.WriteString(column + " IS NULL")
+		} else {
+			w.sb.WriteString(column + " = " + w.param(r.Value))
+		}
+	case ResidualContains:
+		column, err := w.column(r.Field)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(column, "?") {
+			w.sb.WriteString(w.param(r.Value) + " = ANY(" + column + ")")
+			break
+		}
+		parts := strings.Split(column, "?")
+		w.sb.WriteString(parts[0])
+		for _, part := range parts[1:] {
+			w.sb.WriteString(w.param(r.Value) + part)
+		}
+	case ResidualNot:
+		w.sb.WriteString("NOT COALESCE((")
+		if err := w.write(r.Operands[0]); err != nil {
+			return err
+		}
+		w.sb.WriteString("), 1 = 0)")
+	case ResidualAnd, ResidualOr:
+		for i, operand := range r.Operands {
+			if i > 0 {
+				w.sb.WriteString(" " + strings.ToUpper(string(r.Op)) + " ")
+			}
+			nested := operand.Op == ResidualAnd || operand.Op == ResidualOr
+			if nested {
+				w.sb.WriteString("(")
+			}
+			if err := w.write(operand); err != nil {
+				return err
+			}
+			if nested {
+				w.sb.WriteString(")")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown residual operator '%s'", r.Op)
+	}
+
+	return nil
+}
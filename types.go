@@ -4,6 +4,16 @@ type AccessRequest[S any, R any] struct {
 	Subject  S
 	Resource R
 	Action   string
+	// Environment carries request context such as the time of day, client IP
+	// or MFA state. It is optional and may be nil.
+	Environment Attributable
+}
+
+// Environment is a simple attribute bag for AccessRequest.Environment.
+type Environment map[string]any
+
+func (e Environment) GetAttribute(key string) any {
+	return e[key]
 }
 
 type RoleBearer interface {
@@ -0,0 +1,341 @@
+package baccess
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem found by ValidateConfig.
+type ValidationError struct {
+	Role string
+	// Field is the config section the problem was found in: "allow", "deny",
+	// "inherits", "conditions", "algorithm", "matcher" or "policies".
+	Field string
+	// Rule is the index of the offending entry in Field, or -1.
+	Rule int
+	// Value is the offending entry itself, if any.
+	Value   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Role != "" || e.Field == "policies" {
+		fmt.Fprintf(&b, "role '%s': ", e.Role)
+	}
+	b.WriteString(e.Field)
+	if e.Rule >= 0 {
+		fmt.Fprintf(&b, "[%d]", e.Rule)
+	}
+	if e.Value != "" {
+		fmt.Fprintf(&b, " '%s'", e.Value)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Message)
+
+	return b.String()
+}
+
+// ValidationErrors is the list of problems returned by ValidateConfig.
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, e := range v {
+		messages[i] = e.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// LoadConfigStrict is like LoadConfig but rejects unknown fields (e.g. a
+// misspelled "alow") and trailing data instead of silently ignoring them.
+func LoadConfigStrict(r io.Reader, format ConfigFormat) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config JSON: %w", err)
+		}
+		if decoder.More() {
+			return nil, errors.New("failed to parse config JSON: unexpected data after config object")
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format '%s'", format)
+	}
+
+	return &cfg, nil
+}
+
+// ValidateConfig checks cfg for mistakes that BuildEvaluator would accept or
+// only partially report: empty role names, empty or malformed rules and
+// conditions, named conditions that do not compile, predicate names the
+// provider does not know, duplicate rules, rules shadowed by a
+// broader rule of the same role, allow rules that a deny rule of the same role
+// always overrides, inheritance of unknown roles, inheritance cycles and
+// unknown combining algorithms and action matchers. With the "hierarchy"
+// matcher, a rule also shadows or overrides the rules for actions below its
+// own, and actions must be dot-separated names or "*" segments. A nil
+// provider skips predicate lookups.
+//
+// The returned error is a ValidationErrors, or nil if cfg is valid.
+func ValidateConfig[S any, R any](cfg *Config, provider PredicateProvider[S, R]) error {
+	var problems ValidationErrors
+	report := func(role, field string, rule int, value, format string, args ...any) {
+		problems = append(problems, ValidationError{
+			Role:    role,
+			Field:   field,
+			Rule:    rule,
+			Value:   value,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if cfg.Algorithm != "" && !cfg.Algorithm.valid() {
+		report("", "algorithm", -1, string(cfg.Algorithm), "unknown combining algorithm")
+	}
+	if cfg.Matcher != "" && cfg.Matcher.matcher() == nil {
+		report("", "matcher", -1, string(cfg.Matcher), "unknown action matcher")
+	}
+	hierarchical := cfg.Matcher == HierarchyMatching
+	// Without a provider, a condition that is not an expression may still be
+	// a predicate name.
+	known := func(name string) bool {
+		if provider == nil {
+			return true
+		}
+		_, err := provider.GetPredicate(name)
+		return err == nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Conditions)) {
+		if !validConditionName(name) {
+			report("", "conditions", -1, name, "condition name may only contain letters, digits, '_', '-' and '.'")
+		}
+		if _, err := CompileExpression[S, R](cfg.Conditions[name]); err != nil {
+			report("", "conditions", -1, name, "%v", err)
+		}
+	}
+	if provider != nil && len(cfg.Conditions) > 0 {
+		// Compile errors were reported above.
+		provider, _ = compileConditions(cfg, provider)
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(cfg.Policies)) {
+		policy := cfg.Policies[role]
+		if strings.TrimSpace(role) == "" {
+			report(role, "policies", -1, "", "role name is empty")
+		}
+
+		lists := []struct {
+			field string
+			rules []string
+		}{
+			{"deny", policy.Deny},
+			{"allow", policy.Allow},
+		}
+		for _, list := range lists {
+			parsed := make([]parsedRule, len(list.rules))
+			for i, rule := range list.rules {
+				parsed[i] = parseRule(rule, known)
+				if msg := parsed[i].problem(); msg != "" {
+					report(role, list.field, i, rule, "%s", msg)
+					parsed[i].invalid = true
+					continue
+				}
+				if hierarchical {
+					if msg := hierarchyProblem(parsed[i].action); msg != "" {
+						report(role, list.field, i, rule, "%s", msg)
+						parsed[i].invalid = true
+						continue
+					}
+				}
+				if provider != nil && parsed[i].expr != nil {
+					for _, leaf := range parsed[i].expr.leaves(nil) {
+						if _, err := resolveCondition(provider, leaf); err != nil {
+							if leaf.call {
+								report(role, list.field, i, rule, "invalid condition '%s': %v", leaf, err)
+							} else {
+								report(role, list.field, i, rule, "unknown condition '%s': %v", leaf, err)
+							}
+						}
+					}
+				}
+			}
+
+			for i, rule := range parsed {
+				if rule.invalid {
+					continue
+				}
+				if j := slices.Index(list.rules, list.rules[i]); j < i {
+					report(role, list.field, i, rule.raw, "duplicate of %s[%d]", list.field, j)
+					continue
+				}
+				for j, other := range parsed {
+					if i == j || other.invalid || other.raw == rule.raw || !other.covers(rule, cfg.Matcher) {
+						continue
+					}
+					// When two rules cover each other only the later one is redundant.
+					if j > i && rule.covers(other, cfg.Matcher) {
+						continue
+					}
+					report(role, list.field, i, rule.raw, "shadowed by %s[%d] '%s'", list.field, j, other.raw)
+					break
+				}
+			}
+		}
+
+		// Under first-applicable a role's deny rules are ordered before its
+		// allow rules, so only permit-overrides lets such an allow rule apply.
+		if cfg.Algorithm != PermitOverrides {
+			for i, allow := range policy.Allow {
+				allowRule := parseRule(allow, known)
+				if allowRule.problem() != "" {
+					continue
+				}
+				for j, deny := range policy.Deny {
+					denyRule := parseRule(deny, known)
+					if denyRule.problem() == "" && denyRule.covers(allowRule, cfg.Matcher) {
+						report(role, "allow", i, allow, "never applies: overridden by deny[%d] '%s'", j, deny)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(cfg.Inherits)) {
+		for i, inherited := range cfg.Inherits[role] {
+			if _, ok := cfg.Policies[inherited]; ok {
+				continue
+			}
+			if _, ok := cfg.Inherits[inherited]; ok {
+				continue
+			}
+			report(role, "inherits", i, inherited, "inherits unknown role")
+		}
+	}
+	if err := findInheritanceCycle(cfg.Inherits); err != nil {
+		report("", "inherits", -1, "", "%v", err)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return problems
+}
+
+// parsedRule is an "action:condition" rule split the same way BuildEvaluator
+// splits it; a rule without a condition has condition "*".
+type parsedRule struct {
+	raw          string
+	action       string
+	condition    string
+	hasCondition bool
+	// expr is the parsed condition, nil for "*" or a malformed condition.
+	expr    conditionExpr
+	exprErr error
+	invalid bool
+}
+
+func parseRule(rule string, known func(name string) bool) parsedRule {
+	action, condition, hasCondition := strings.Cut(rule, ":")
+	if !hasCondition {
+		condition = "*"
+	}
+
+	r := parsedRule{raw: rule, action: action, condition: condition, hasCondition: hasCondition}
+	if condition != "*" && condition != "" {
+		r.expr, r.exprErr = parseRuleCondition(condition, known)
+	}
+
+	return r
+}
+
+func (r parsedRule) problem() string {
+	switch {
+	case strings.TrimSpace(r.raw) == "":
+		return "rule is empty"
+	case r.action == "":
+		return "rule has no action"
+	case r.hasCondition && r.condition == "":
+		return "rule has an empty condition"
+	case r.exprErr != nil:
+		return r.exprErr.Error()
+	}
+
+	return ""
+}
+
+// covers reports whether r, granted to a role, applies to every request that
+// other applies to under matching. Under the hierarchy matcher, r's action
+// also covers the actions below it, and a conditional rule covers the rules
+// with the same condition below it. Under the strict and glob matchers, a rule
+// without a condition does not apply to actions with one, so it only covers
+// other rules without a condition.
+func (r parsedRule) covers(other parsedRule, matching ActionMatching) bool {
+	switch matching {
+	case HierarchyMatching:
+		if r.condition != "*" && r.condition != other.condition {
+			return false
+		}
+		// A "*" segment of other is compared literally, so that only a "*"
+		// segment of r covers it.
+		return HierarchyMatcher{}.Match(r.action, other.action)
+	case StrictMatching, GlobMatching:
+		if r.action == "*" && r.condition == "*" {
+			return true
+		}
+		if r.condition != "*" {
+			return r.raw == other.raw
+		}
+		return r.action == other.action && (r.hasCondition || !other.hasCondition)
+	}
+
+	if r.condition != "*" {
+		return r.raw == other.raw
+	}
+
+	return r.action == "*" || r.action == other.action
+}
+
+// hierarchyProblem describes what is wrong with a hierarchical action, or
+// returns "" if it is well formed.
+func hierarchyProblem(action string) string {
+	if action == "*" {
+		return ""
+	}
+
+	for segment := range strings.SplitSeq(action, ".") {
+		switch {
+		case segment == "":
+			return "action has an empty segment"
+		case segment != "*" && strings.Contains(segment, "*"):
+			return fmt.Sprintf("action segment '%s' may only be '*' or a name", segment)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,176 @@
+package baccess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+		},
+	}
+
+	valid := &baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{
+			"editor": {Allow: []string{"read", "delete:isOwner"}, Deny: []string{"publish"}},
+			"viewer": {Allow: []string{"read:*"}},
+		},
+		Inherits: map[string][]string{"editor": {"viewer"}},
+	}
+	assert.NoError(t, baccess.ValidateConfig(valid, provider))
+
+	invalid := &baccess.Config{
+		Algorithm: "most-permissive",
+		Policies: map[string]baccess.RolePolicyConfig{
+			"": {Allow: []string{"read"}},
+			"editor": {
+				Allow: []string{"read", "read:", ":isOwner", "", "edit:isCollaborator", "read", "read:isOwner", "read:*", "delete:isOwner", "edit:isOwner|!isArchived", "edit:isOwner&("},
+				Deny:  []string{"delete"},
+			},
+			"admin": {Allow: []string{"*", "write"}},
+		},
+		Inherits: map[string][]string{
+			"admin":  {"editor", "auditor"},
+			"editor": {"admin"},
+		},
+	}
+
+	err := baccess.ValidateConfig(invalid, provider)
+	var problems baccess.ValidationErrors
+	assert.True(t, errors.As(err, &problems))
+
+	messages := make([]string, len(problems))
+	for i, p := range problems {
+		messages[i] = p.Error()
+	}
+	assert.Equal(t, []string{
+		"algorithm 'most-permissive': unknown combining algorithm",
+		"role '': policies: role name is empty",
+		"role 'admin': allow[1] 'write': shadowed by allow[0] '*'",
+		"role 'editor': allow[1] 'read:': rule has an empty condition",
+		"role 'editor': allow[2] ':isOwner': rule has no action",
+		"role 'editor': allow[3]: rule is empty",
+		"role 'editor': allow[4] 'edit:isCollaborator': unknown condition 'isCollaborator': predicate not found",
+		"role 'editor': allow[9] 'edit:isOwner|!isArchived': unknown condition 'isArchived': predicate not found",
+		"role 'editor': allow[10] 'edit:isOwner&(': invalid condition 'isOwner&(': column 10: expected predicate name, found end of condition",
+		"role 'editor': allow[5] 'read': duplicate of allow[0]",
+		"role 'editor': allow[6] 'read:isOwner': shadowed by allow[0] 'read'",
+		"role 'editor': allow[7] 'read:*': shadowed by allow[0] 'read'",
+		"role 'editor': allow[8] 'delete:isOwner': never applies: overridden by deny[0] 'delete'",
+		"role 'admin': inherits[1] 'auditor': inherits unknown role",
+		"inherits: role inheritance cycle: admin -> editor -> admin",
+	}, messages)
+
+	assert.Equal(t, baccess.ValidationError{Role: "editor", Field: "allow", Rule: 1, Value: "read:", Message: "rule has an empty condition"}, problems[3])
+	assert.Equal(t, strings.Join(messages, "\n"), err.Error())
+
+	// Without a provider, conditions are not looked up.
+	err = baccess.ValidateConfig[auth_test_utils.MockSubject, auth_test_utils.MockResource](&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"edit:isCollaborator", "read:team:owner"}}},
+	}, nil)
+	assert.NoError(t, err)
+
+	// A predicate name that is not valid in expressions is a whole condition.
+	provider.Predicates["team:owner"] = isOwner()
+	err = baccess.ValidateConfig(&baccess.Config{
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"read:team:owner"}}},
+	}, provider)
+	assert.NoError(t, err)
+
+	// Under the strict matcher a rule without a condition does not apply to
+	// actions with one.
+	err = baccess.ValidateConfig(&baccess.Config{
+		Matcher:  baccess.StrictMatching,
+		Policies: map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"read", "read:isOwner", "share:*", "share:isOwner", "edit", "edit:*"}}},
+	}, provider)
+	assert.EqualError(t, err, strings.Join([]string{
+		"role 'editor': allow[3] 'share:isOwner': shadowed by allow[2] 'share:*'",
+		"role 'editor': allow[4] 'edit': shadowed by allow[5] 'edit:*'",
+	}, "\n"))
+
+	// Deny rules do not override allow rules under permit-overrides.
+	err = baccess.ValidateConfig(&baccess.Config{
+		Algorithm: baccess.PermitOverrides,
+		Policies:  map[string]baccess.RolePolicyConfig{"editor": {Allow: []string{"delete"}, Deny: []string{"delete"}}},
+	}, provider)
+	assert.NoError(t, err)
+}
+
+func TestValidateConfig_Hierarchy(t *testing.T) {
+	provider := &MockPredicateProvider{
+		Predicates: map[string]baccess.Predicate[baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]]{
+			"isOwner": isOwner(),
+		},
+	}
+
+	cfg := &baccess.Config{
+		Matcher: baccess.HierarchyMatching,
+		Policies: map[string]baccess.RolePolicyConfig{
+			"accountant": {
+				Allow: []string{"billing.invoice", "billing.invoice.read", "billing.*.refund:isOwner", "billing.*:isOwner", "billing..read", "billing.inv*"},
+				Deny:  []string{"billing.invoice.void"},
+			},
+			"auditor": {Allow: []string{"billing.invoice.void", "billing.*"}, Deny: []string{"billing.*.delete"}},
+		},
+	}
+
+	err := baccess.ValidateConfig(cfg, provider)
+	var problems baccess.ValidationErrors
+	assert.True(t, errors.As(err, &problems))
+
+	messages := make([]string, len(problems))
+	for i, p := range problems {
+		messages[i] = p.Error()
+	}
+	assert.Equal(t, []string{
+		"role 'accountant': allow[4] 'billing..read': action has an empty segment",
+		"role 'accountant': allow[5] 'billing.inv*': action segment 'inv*' may only be '*' or a name",
+		"role 'accountant': allow[1] 'billing.invoice.read': shadowed by allow[0] 'billing.invoice'",
+		"role 'accountant': allow[2] 'billing.*.refund:isOwner': shadowed by allow[3] 'billing.*:isOwner'",
+		"role 'auditor': allow[0] 'billing.invoice.void': shadowed by allow[1] 'billing.*'",
+	}, messages)
+
+	// The same rules are independent under the default matcher.
+	cfg.Matcher = ""
+	assert.NoError(t, baccess.ValidateConfig(cfg, provider))
+
+	cfg.Matcher = "tree"
+	assert.EqualError(t, baccess.ValidateConfig(cfg, provider), "matcher 'tree': unknown action matcher")
+
+	// A deny rule overrides the allow rules below it.
+	err = baccess.ValidateConfig(&baccess.Config{
+		Matcher:  baccess.HierarchyMatching,
+		Policies: map[string]baccess.RolePolicyConfig{"clerk": {Allow: []string{"billing.invoice.read", "billing.report"}, Deny: []string{"billing.invoice"}}},
+	}, provider)
+	assert.EqualError(t, err, "role 'clerk': allow[0] 'billing.invoice.read': never applies: overridden by deny[0] 'billing.invoice'")
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	cfg, err := baccess.LoadConfigStrict(strings.NewReader(`{"policies":{"viewer":{"allow":["read"]}}}`), baccess.FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, cfg.Policies["viewer"].Allow)
+
+	_, err = baccess.LoadConfigStrict(strings.NewReader(`{"policies":{"viewer":{"alow":["read"]}}}`), baccess.FormatJSON)
+	assert.EqualError(t, err, `failed to parse config JSON: json: unknown field "alow"`)
+
+	_, err = baccess.LoadConfigStrict(strings.NewReader(`{"policies":{}} {"policies":{}}`), baccess.FormatJSON)
+	assert.ErrorContains(t, err, "unexpected data after config object")
+
+	_, err = baccess.LoadConfigStrict(strings.NewReader("policies:\n  viewer:\n    alow: [read]\n"), baccess.FormatYAML)
+	assert.ErrorContains(t, err, "failed to parse config YAML")
+	assert.ErrorContains(t, err, "line 3: field alow not found")
+
+	cfg, err = baccess.LoadConfigStrict(strings.NewReader(""), baccess.FormatYAML)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Policies)
+
+	_, err = baccess.LoadConfigStrict(strings.NewReader(""), "ini")
+	assert.EqualError(t, err, "unsupported config format 'ini'")
+}
@@ -0,0 +1,202 @@
+package baccess
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// WatchEvent reports the outcome of a reload attempt by a Watcher.
+type WatchEvent struct {
+	Path string
+	Time time.Time
+	// Err is nil when the new config was published, otherwise it explains why
+	// the previous evaluator is still being served.
+	Err error
+}
+
+type WatcherOption func(*watcherOptions)
+
+type watcherOptions struct {
+	interval       time.Duration
+	onReload       func(WatchEvent)
+	evaluatorOpts  []EvaluatorOption
+	eventsCapacity int
+}
+
+// WithPollInterval sets how often the watched file is checked (default 2s).
+// The interval must be positive.
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(o *watcherOptions) {
+		o.interval = interval
+	}
+}
+
+// WithReloadHook registers a callback invoked after every reload attempt.
+func WithReloadHook(hook func(WatchEvent)) WatcherOption {
+	return func(o *watcherOptions) {
+		o.onReload = hook
+	}
+}
+
+// WithEvaluatorOptions passes options to BuildEvaluator on every reload.
+func WithEvaluatorOptions(opts ...EvaluatorOption) WatcherOption {
+	return func(o *watcherOptions) {
+		o.evaluatorOpts = append(o.evaluatorOpts, opts...)
+	}
+}
+
+// Watcher polls a policy file and rebuilds the evaluator whenever the file
+// content changes. When the new file cannot be loaded or built, the last good
+// evaluator keeps serving and the failure is reported as a WatchEvent.
+type Watcher[S RoleBearer, R any] struct {
+	path     string
+	rbac     *RBAC[S, R]
+	provider PredicateProvider[S, R]
+	options  watcherOptions
+	holder   *EvaluatorHolder[S, R]
+	events   chan WatchEvent
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	contents []byte
+}
+
+// NewWatcher loads the file at path and builds the initial evaluator. It fails
+// if the initial config cannot be loaded or built, since there is no previous
+// evaluator to fall back to, or if the poll interval is not positive.
+func NewWatcher[S RoleBearer, R any](
+	path string,
+	rbac *RBAC[S, R],
+	provider PredicateProvider[S, R],
+	opts ...WatcherOption,
+) (*Watcher[S, R], error) {
+	options := watcherOptions{interval: 2 * time.Second, eventsCapacity: 16}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.interval <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive, got %s", options.interval)
+	}
+
+	w := &Watcher[S, R]{
+		path:     path,
+		rbac:     rbac,
+		provider: provider,
+		options:  options,
+		holder:   NewEvaluatorHolder[S, R](nil),
+		events:   make(chan WatchEvent, options.eventsCapacity),
+	}
+
+	if err := w.check(true); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Holder returns the holder through which the current evaluator is published.
+func (w *Watcher[S, R]) Holder() *EvaluatorHolder[S, R] {
+	return w.holder
+}
+
+// Evaluate evaluates req against the most recently loaded evaluator.
+func (w *Watcher[S, R]) Evaluate(req AccessRequest[S, R]) bool {
+	return w.holder.Evaluate(req)
+}
+
+// Events delivers reload events. The channel is buffered; events are dropped
+// rather than blocking the watcher when nobody is receiving.
+func (w *Watcher[S, R]) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Run polls the file until ctx is done.
+func (w *Watcher[S, R]) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(false)
+		}
+	}
+}
+
+// Reload re-reads the file immediately, even if it looks unchanged.
+func (w *Watcher[S, R]) Reload() error {
+	return w.check(true)
+}
+
+// check reloads the file if its content changed, or unconditionally when
+// force is set. The event is published after the lock is released, so that
+// hooks may call Reload.
+func (w *Watcher[S, R]) check(force bool) error {
+	event := w.reload(force)
+	if event == nil {
+		return nil
+	}
+	w.publish(*event)
+
+	return event.Err
+}
+
+// reload does the work of check under the lock and returns the event to
+// publish, or nil if the file is unchanged.
+func (w *Watcher[S, R]) reload(force bool) *WatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	event := &WatchEvent{Path: w.path, Time: time.Now()}
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		event.Err = fmt.Errorf("failed to stat config file: %w", err)
+		return event
+	}
+	if !force && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil
+	}
+
+	contents, err := os.ReadFile(w.path)
+	if err != nil {
+		event.Err = fmt.Errorf("failed to read config file: %w", err)
+		return event
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	if !force && bytes.Equal(contents, w.contents) {
+		return nil
+	}
+
+	event.Err = w.holder.Reload(func() (*Evaluator[S, R], error) {
+		cfg, err := parseConfigFile(w.path, contents)
+		if err != nil {
+			return nil, err
+		}
+
+		return BuildEvaluator(cfg, w.rbac, w.provider, w.options.evaluatorOpts...)
+	})
+	if event.Err == nil {
+		w.contents = contents
+	}
+
+	return event
+}
+
+func (w *Watcher[S, R]) publish(event WatchEvent) {
+	if w.options.onReload != nil {
+		w.options.onReload(event)
+	}
+
+	select {
+	case w.events <- event:
+	default:
+	}
+}
@@ -0,0 +1,146 @@
+package baccess_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brian-nunez/baccess"
+	auth_test_utils "github.com/brian-nunez/baccess/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	// Make sure the change is visible even on filesystems with coarse mtimes.
+	later := time.Now().Add(time.Duration(len(contents)) * time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func nextEvent(t *testing.T, events <-chan baccess.WatchEvent) baccess.WatchEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+		return baccess.WatchEvent{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	writeConfigFile(t, path, `{"policies":{"user":{"allow":["read"]}}}`)
+
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+
+	var hooked []baccess.WatchEvent
+	watcher, err := baccess.NewWatcher(path, rbac, provider,
+		baccess.WithPollInterval(5*time.Millisecond),
+		baccess.WithReloadHook(func(e baccess.WatchEvent) { hooked = append(hooked, e) }),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, nextEvent(t, watcher.Events()).Err)
+
+	req := func(action string) baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource] {
+		return baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+			Subject: auth_test_utils.MockSubject{Roles: []string{"user"}},
+			Action:  action,
+		}
+	}
+	assert.True(t, watcher.Evaluate(req("read")))
+	assert.False(t, watcher.Evaluate(req("write")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx)
+		close(done)
+	}()
+
+	writeConfigFile(t, path, `{"policies":{"user":{"allow":["read","write"]}}}`)
+	event := nextEvent(t, watcher.Events())
+	assert.NoError(t, event.Err)
+	assert.Equal(t, path, event.Path)
+	assert.True(t, watcher.Evaluate(req("write")))
+
+	// A file that fails to parse keeps the last good evaluator.
+	writeConfigFile(t, path, `{"policies":`)
+	event = nextEvent(t, watcher.Events())
+	assert.ErrorContains(t, event.Err, "failed to parse config JSON")
+	assert.True(t, watcher.Evaluate(req("write")))
+
+	// So does a file that references an unknown predicate.
+	writeConfigFile(t, path, `{"policies":{"user":{"allow":["read:missing"]}}}`)
+	event = nextEvent(t, watcher.Events())
+	assert.ErrorContains(t, event.Err, "failed to get predicate 'missing'")
+	assert.True(t, watcher.Evaluate(req("write")))
+
+	cancel()
+	<-done
+
+	assert.Len(t, hooked, 4)
+
+	writeConfigFile(t, path, `{"policies":{"user":{"allow":["delete"]}}}`)
+	assert.NoError(t, watcher.Reload())
+	assert.True(t, watcher.Evaluate(req("delete")))
+	assert.False(t, watcher.Holder().Evaluate(req("write")))
+}
+
+func TestWatcher_HookReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	writeConfigFile(t, path, `{"policies":{"user":{"allow":["read"]}}}`)
+
+	// The hook runs without the watcher's lock held, so it may reload, e.g. to
+	// retry after a failure.
+	var watcher *baccess.Watcher[auth_test_utils.MockSubject, auth_test_utils.MockResource]
+	var retried error
+	hook := func(e baccess.WatchEvent) {
+		if e.Err != nil && retried == nil {
+			writeConfigFile(t, path, `{"policies":{"user":{"allow":["write"]}}}`)
+			retried = watcher.Reload()
+		}
+	}
+	watcher, err := baccess.NewWatcher(path, baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource](), &MockPredicateProvider{}, baccess.WithReloadHook(hook))
+	require.NoError(t, err)
+
+	writeConfigFile(t, path, `{"policies":`)
+	done := make(chan error)
+	go func() { done <- watcher.Reload() }()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload from the reload hook deadlocked")
+	}
+	assert.ErrorContains(t, err, "failed to parse config JSON")
+	assert.NoError(t, retried)
+	assert.True(t, watcher.Evaluate(baccess.AccessRequest[auth_test_utils.MockSubject, auth_test_utils.MockResource]{
+		Subject: auth_test_utils.MockSubject{Roles: []string{"user"}},
+		Action:  "write",
+	}))
+}
+
+func TestNewWatcher_InitialLoadFails(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+
+	_, err := baccess.NewWatcher(filepath.Join(t.TempDir(), "missing.json"), rbac, provider)
+	assert.ErrorContains(t, err, "failed to stat config file")
+}
+
+func TestNewWatcher_InvalidPollInterval(t *testing.T) {
+	rbac := baccess.NewRBAC[auth_test_utils.MockSubject, auth_test_utils.MockResource]()
+	provider := &MockPredicateProvider{}
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, path, `{"policies": {"user": {"allow": ["read"]}}}`)
+
+	_, err := baccess.NewWatcher(path, rbac, provider, baccess.WithPollInterval(0))
+	assert.EqualError(t, err, "poll interval must be positive, got 0s")
+	_, err = baccess.NewWatcher(path, rbac, provider, baccess.WithPollInterval(-time.Second))
+	assert.EqualError(t, err, "poll interval must be positive, got -1s")
+}